@@ -37,6 +37,9 @@ func main() {
 		},
 	}
 
+	cmd.Flags().StringVar(&accept.RulesFile, "accept-rules-file", accept.RulesFile, "The yaml file contains rules set clusterset and labels on ManagedCluster when accepted.")
+	cmd.Flags().BoolVar(&accept.PreAuthorizedOnly, "pre-authorized-only", accept.PreAuthorizedOnly, "Only approve ManagedClusters pre-authorized by join-kit.")
+
 	klog.InitFlags(flag.CommandLine)
 
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiServer     = "http://127.0.0.1:8080"
+	clientTimeout = time.Second * 10
+)
+
+func newAppsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "apps [app-name]",
+		Short:        "Show applications across all clusters",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apps := []report.AppView{}
+			if err := getAPI("/api/v1/apps", &apps); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return printAppWorkloads(apps, args[0])
+			}
+			return printApps(apps)
+		},
+	}
+	cmd.Flags().StringVar(&apiServer, "server", apiServer, "The address of clustermanager api server.")
+	return cmd
+}
+
+func printApps(apps []report.AppView) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "APP\tCLUSTERS\tWORKLOADS\tREADY\tCPU\tMEMORY\tIMAGE-SKEW")
+	for _, app := range apps {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d/%d\t%s\t%s\t%t\n",
+			app.Name,
+			strings.Join(app.Clusters, ","),
+			len(app.Workloads),
+			app.ReadyReplicas, app.Replicas,
+			app.Requests.Cpu().String(),
+			app.Requests.Memory().String(),
+			app.ImageSkew,
+		)
+	}
+	return w.Flush()
+}
+
+func printAppWorkloads(apps []report.AppView, name string) error {
+	for _, app := range apps {
+		if app.Name != name {
+			continue
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "CLUSTER\tNAMESPACE\tKIND\tNAME\tREADY\tIMAGES")
+		for _, wl := range app.Workloads {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+				wl.ClusterName, wl.Namespace, wl.Kind, wl.Name,
+				wl.ReadyReplicas, wl.Replicas,
+				strings.Join(wl.Images, ","),
+			)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("app %s not found", name)
+}
+
+func getAPI(path string, v interface{}) error {
+	cli := &http.Client{Timeout: clientTimeout}
+	resp, err := cli.Get(strings.TrimSuffix(apiServer, "/") + path)
+	if err != nil {
+		return fmt.Errorf("request %s failed: %+v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %s failed with status %s", path, resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s response failed: %+v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/decommission"
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/champly/clustermanager/pkg/lifecycle"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newDecommissionCmd() *cobra.Command {
+	var (
+		wait    = false
+		timeout = time.Minute * 15
+	)
+	cmd := &cobra.Command{
+		Use:          "decommission <cluster-name>",
+		Short:        "Mark a cluster decommissioning, cleanup is done by clustermanager",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if err := kube.InitManagerPlaneClusterClient(ctx); err != nil {
+				return err
+			}
+
+			clusterName := args[0]
+			patch, _ := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						lifecycle.DecommissionAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			})
+			mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}
+			if err := kube.ManagerPlaneClusterClient.Patch(mc, client.RawPatch(types.MergePatchType, patch)); err != nil {
+				return fmt.Errorf("mark ManagedCluster %s decommissioning failed: %+v", clusterName, err)
+			}
+			fmt.Printf("ManagedCluster %s marked decommissioning\n", clusterName)
+			if !wait {
+				return nil
+			}
+			return waitDecommissioned(ctx, clusterName)
+		},
+	}
+	cmd.Flags().BoolVar(&wait, "wait", wait, "Wait until the ManagedCluster removed and print progress.")
+	cmd.Flags().DurationVar(&timeout, "timeout", timeout, "The max time to wait.")
+	return cmd
+}
+
+func waitDecommissioned(ctx context.Context, clusterName string) error {
+	reader := kube.ManagerPlaneClusterClient.GetCtrlRtManager().GetAPIReader()
+	printed := 0
+	for {
+		mc := &clusterapiv1.ManagedCluster{}
+		err := reader.Get(ctx, types.NamespacedName{Name: clusterName}, mc)
+		if apierrors.IsNotFound(err) {
+			fmt.Printf("ManagedCluster %s removed\n", clusterName)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Get ManagedCluster %s failed: %+v", clusterName, err)
+		}
+
+		progress := &decommission.Progress{}
+		if data, ok := mc.Annotations[decommission.ProgressAnnotation]; ok {
+			if err = json.Unmarshal([]byte(data), progress); err != nil {
+				fmt.Printf("parse decommission progress of %s failed: %+v\n", clusterName, err)
+				progress = &decommission.Progress{}
+			}
+		}
+		// progress may be rewritten with fewer steps, such as restarted after invalid annotation
+		if printed > len(progress.Steps) {
+			printed = 0
+		}
+		for _, s := range progress.Steps[printed:] {
+			fmt.Printf("%s step %s finished\n", s.CompletionTime.Format(time.RFC3339), s.Name)
+		}
+		printed = len(progress.Steps)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait ManagedCluster %s removed failed: %+v", clusterName, ctx.Err())
+		case <-time.After(time.Second * 5):
+		}
+	}
+}
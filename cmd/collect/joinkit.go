@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/joinkit"
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/spf13/cobra"
+)
+
+func newJoinKitCmd() *cobra.Command {
+	var (
+		labels    = map[string]string{}
+		outputDir = ""
+	)
+	cmd := &cobra.Command{
+		Use:          "join-kit <cluster-name>",
+		Short:        "Pre-authorize a cluster on hub and generate the bootstrap kubeconfig and manifests applied on it",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := kube.InitManagerPlaneClusterClient(ctx); err != nil {
+				return err
+			}
+
+			kit, err := joinkit.Generate(ctx, args[0], labels)
+			if err != nil {
+				return err
+			}
+			if outputDir == "" {
+				fmt.Printf("# join kit of cluster %s, bootstrap token expires at %s\n", kit.ClusterName, kit.ExpirationTime.Format(time.RFC3339))
+				printOperatorPrerequisite(kit, "# ")
+				if _, err = os.Stdout.Write(kit.OperatorManifests); err != nil {
+					return err
+				}
+				_, err = os.Stdout.Write(kit.Manifests)
+				return err
+			}
+			return writeJoinKit(kit, outputDir)
+		},
+	}
+	cmd.Flags().StringToStringVar(&labels, "labels", labels, "The labels set on pre-created ManagedCluster.")
+	cmd.Flags().StringVar(&outputDir, "output-dir", outputDir, "The directory bootstrap kubeconfig and spoke manifests are written to, default print manifests to stdout.")
+	cmd.Flags().DurationVar(&joinkit.TokenTTL, "token-ttl", joinkit.TokenTTL, "The lifetime of bootstrap token, cluster must register before it expires.")
+	cmd.Flags().StringVar(&joinkit.HubAPIServer, "hub-api-server", joinkit.HubAPIServer, "The hub apiserver address spoke connects to, default is the host of hub kubeconfig.")
+	cmd.Flags().StringVar(&joinkit.BootstrapNamespace, "bootstrap-namespace", joinkit.BootstrapNamespace, "The hub namespace bootstrap ServiceAccount is created in.")
+	cmd.Flags().StringVar(&joinkit.BootstrapClusterRole, "bootstrap-clusterrole", joinkit.BootstrapClusterRole, "The hub ClusterRole bound to bootstrap ServiceAccount.")
+	cmd.Flags().StringVar(&joinkit.KlusterletNamespace, "klusterlet-namespace", joinkit.KlusterletNamespace, "The spoke namespace klusterlet agents run in.")
+	cmd.Flags().StringVar(&joinkit.RegistrationImage, "registration-image", joinkit.RegistrationImage, "The registration agent image of klusterlet.")
+	cmd.Flags().BoolVar(&joinkit.IncludeOperator, "include-operator", joinkit.IncludeOperator, "Include klusterlet operator and its CRD in spoke manifests, disable when operator is already installed on spoke.")
+	cmd.Flags().StringVar(&joinkit.OperatorNamespace, "operator-namespace", joinkit.OperatorNamespace, "The spoke namespace klusterlet operator runs in.")
+	cmd.Flags().StringVar(&joinkit.OperatorImage, "operator-image", joinkit.OperatorImage, "The klusterlet operator image.")
+	cmd.Flags().StringVar(&joinkit.WorkImage, "work-image", joinkit.WorkImage, "The work agent image of klusterlet.")
+	return cmd
+}
+
+func writeJoinKit(kit *joinkit.Kit, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create output dir %s failed: %+v", dir, err)
+	}
+	files := map[string][]byte{
+		"bootstrap-kubeconfig.yaml": kit.BootstrapKubeconfig,
+		"spoke-manifests.yaml":      kit.Manifests,
+	}
+	if len(kit.OperatorManifests) > 0 {
+		files["operator-manifests.yaml"] = kit.OperatorManifests
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		// contains bootstrap token
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			return fmt.Errorf("write %s failed: %+v", file, err)
+		}
+	}
+	fmt.Printf("join kit of cluster %s written to %s, bootstrap token expires at %s\n", kit.ClusterName, dir, kit.ExpirationTime.Format(time.RFC3339))
+	printOperatorPrerequisite(kit, "")
+	if len(kit.OperatorManifests) > 0 {
+		fmt.Printf("run on spoke: kubectl apply -f %s\n", filepath.Join(dir, "operator-manifests.yaml"))
+		fmt.Printf("run on spoke: kubectl wait --for condition=established crd/%s\n", joinkit.KlusterletCRDName)
+	}
+	fmt.Printf("run on spoke: kubectl apply -f %s\n", filepath.Join(dir, "spoke-manifests.yaml"))
+	return nil
+}
+
+// printOperatorPrerequisite Klusterlet is only reconciled by klusterlet operator, applying it alone joins nothing
+func printOperatorPrerequisite(kit *joinkit.Kit, prefix string) {
+	if len(kit.OperatorManifests) > 0 {
+		fmt.Printf("%sklusterlet operator is included, CRD %s must be established before Klusterlet is applied, rerun apply if it's rejected\n", prefix, joinkit.KlusterletCRDName)
+		return
+	}
+	fmt.Printf("%sprerequisite: klusterlet operator and CRD %s must already be installed on spoke, otherwise cluster never registers\n", prefix, joinkit.KlusterletCRDName)
+}
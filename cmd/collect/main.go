@@ -5,8 +5,15 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/champly/clustermanager/pkg/advisory"
+	"github.com/champly/clustermanager/pkg/autolabel"
 	"github.com/champly/clustermanager/pkg/collect"
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/decommission"
 	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/champly/clustermanager/pkg/lifecycle"
+	"github.com/champly/clustermanager/pkg/report"
+	"github.com/champly/clustermanager/pkg/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
@@ -33,10 +40,81 @@ func main() {
 			if err != nil {
 				return err
 			}
+
+			lifecycleCtrl, err := lifecycle.New(ctx)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := lifecycleCtrl.Start(); err != nil {
+					klog.Error(err)
+				}
+			}()
+
+			autolabelCtrl, err := autolabel.New(ctx)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := autolabelCtrl.Start(); err != nil {
+					klog.Error(err)
+				}
+			}()
+
+			decommissionCtrl, err := decommission.New(ctx)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := decommissionCtrl.Start(); err != nil {
+					klog.Error(err)
+				}
+			}()
+
+			go func() {
+				if err := server.New(ctx).Start(); err != nil {
+					klog.Error(err)
+				}
+			}()
+
 			return ctrl.Start()
 		},
 	}
 
+	cmd.Flags().StringSliceVar(&resource.ShowLabelKeys, "show-label-keys", resource.ShowLabelKeys, "The label keys identify the application of workload, first non-empty value is used.")
+	cmd.Flags().StringVar(&resource.ShowLabelKey, "show-label-key", resource.ShowLabelKey, "The label key identify the application of workload, replaces the first of --show-label-keys.")
+	cmd.Flags().MarkDeprecated("show-label-key", "use --show-label-keys instead")
+	cmd.Flags().StringVar(&resource.TeamLabelKey, "team-label-key", resource.TeamLabelKey, "The namespace label key used to group namespaces by team.")
+	cmd.Flags().StringSliceVar(&resource.OwnerAnnotationKeys, "owner-annotation-keys", resource.OwnerAnnotationKeys, "The namespace annotation keys recorded as owner information.")
+	cmd.Flags().StringVar(&server.ListenAddr, "api-addr", server.ListenAddr, "The address the api server binds to.")
+	cmd.Flags().StringVar(&report.CIDRSupernet, "cidr-supernet", report.CIDRSupernet, "The supernet cluster pod and service ranges are allocated from, used to suggest free ranges.")
+	cmd.Flags().IntVar(&report.CIDRSuggestPrefixLen, "cidr-suggest-prefix-len", report.CIDRSuggestPrefixLen, "The prefix length of suggested free ranges.")
+	cmd.Flags().IntVar(&report.CIDRSuggestCount, "cidr-suggest-count", report.CIDRSuggestCount, "The max count of suggested free ranges.")
+
+	cmd.Flags().StringVar(&report.PricingFile, "pricing-file", report.PricingFile, "The yaml file contains price per vCPU-hour, GiB-hour and GPU-hour, used to estimate cost.")
+	cmd.Flags().StringSliceVar(&report.GPUResourceNames, "gpu-resource-names", report.GPUResourceNames, "The resource names priced as gpu.")
+
+	cmd.Flags().StringVar(&resource.UpgradeTargetVersion, "target-kubernetes-version", resource.UpgradeTargetVersion, "The kubernetes version clusters plan to upgrade to, default target of upgrade report.")
+	cmd.Flags().StringVar(&resource.SecurityTargetLevel, "security-target-level", resource.SecurityTargetLevel, "The Pod Security Standards level workloads must meet to be compliant, one of privileged, baseline and restricted.")
+	cmd.Flags().StringSliceVar(&resource.RBACIgnoreSubjectPrefixes, "rbac-ignore-subject-prefixes", resource.RBACIgnoreSubjectPrefixes, "The subject prefixes such as User:system:kube- not reported by rbac audit.")
+	cmd.Flags().StringVar(&report.RBACBaselineFile, "rbac-baseline-file", report.RBACBaselineFile, "The yaml file contains risky rbac grants allowed in the fleet.")
+	cmd.Flags().DurationVar(&resource.CertWarnThreshold, "cert-warn-threshold", resource.CertWarnThreshold, "The certificates expire within it are reported as Warning.")
+	cmd.Flags().DurationVar(&resource.CertCriticalThreshold, "cert-critical-threshold", resource.CertCriticalThreshold, "The certificates expire within it are reported as Critical.")
+	cmd.Flags().StringSliceVar(&resource.CertSecretNamespaces, "cert-secret-namespaces", resource.CertSecretNamespaces, "The namespaces whose TLS secrets expiry are tracked.")
+	cmd.Flags().StringVar(&resource.HubKubeconfigSecret, "hub-kubeconfig-secret", resource.HubKubeconfigSecret, "The namespace/name of registration agent secret contains hub client certificate.")
+	cmd.Flags().StringVar(&advisory.AdvisoryFile, "advisory-file", advisory.AdvisoryFile, "The OSV json file or directory matched against collected images, synced separately for offline use.")
+	cmd.Flags().StringVar(&report.EOLBeforeVersion, "eol-before-version", report.EOLBeforeVersion, "The minor versions older than it are end of life, default is three minors supported before target.")
+	cmd.Flags().StringVar(&lifecycle.HooksFile, "lifecycle-hooks-file", lifecycle.HooksFile, "The yaml file contains webhooks and ManifestWorks run when cluster entering a lifecycle phase.")
+	cmd.Flags().DurationVar(&lifecycle.ResyncInterval, "lifecycle-resync-interval", lifecycle.ResyncInterval, "The interval cluster lifecycle phase is re-evaluated against collect results.")
+	cmd.Flags().StringVar(&autolabel.MappingFile, "auto-label-mapping-file", autolabel.MappingFile, "The yaml file contains labels derived from collected facts and written on ManagedCluster.")
+	cmd.Flags().DurationVar(&autolabel.ResyncInterval, "auto-label-resync-interval", autolabel.ResyncInterval, "The interval ManagedCluster labels are re-derived from collected facts.")
+	cmd.Flags().DurationVar(&decommission.DrainTimeout, "decommission-drain-timeout", decommission.DrainTimeout, "The max time waiting for ManifestWorks drained when decommission, remaining ones are orphaned.")
+	cmd.Flags().StringVar(&decommission.GatewaySecretNamespace, "cluster-gateway-secret-namespace", decommission.GatewaySecretNamespace, "The namespace of secrets backing ClusterGateway, same as --secret-namespace of cluster-gateway.")
+
+	cmd.AddCommand(newAppsCmd())
+	cmd.AddCommand(newJoinKitCmd())
+	cmd.AddCommand(newDecommissionCmd())
+
 	klog.InitFlags(flag.CommandLine)
 
 	cmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
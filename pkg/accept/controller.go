@@ -20,7 +20,6 @@ import (
 )
 
 var (
-	clusterLabel   = "open-cluster-management.io/cluster-name"
 	controllerName = "ClusterManagerAutoAccept"
 )
 
@@ -28,12 +27,19 @@ type Controller struct {
 	ctx    context.Context
 	client api.MingleClient
 	queue  api.WorkQueue
+	rules  *Rules
 }
 
 func New(ctx context.Context) (*Controller, error) {
+	rules, err := LoadRules(RulesFile)
+	if err != nil {
+		return nil, err
+	}
+
 	ctrl := &Controller{
 		ctx:    ctx,
 		client: kube.ManagerPlaneClusterClient,
+		rules:  rules,
 	}
 
 	queue, err := workqueue.Complted(workqueue.NewQueueConfig(ctrl)).NewQueue()
@@ -47,7 +53,7 @@ func New(ctx context.Context) (*Controller, error) {
 		handler.NewResourceEventHandler(
 			ctrl.queue,
 			handler.NewDefaultTransformNamespacedNameEventHandler(),
-			&predicate{},
+			&predicate{rules: rules},
 		),
 	)
 	if err != nil {
@@ -68,7 +74,12 @@ func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, ti
 		return api.Done, 0, fmt.Errorf("Get ManagedCluster %s failed:%+v", key.String(), err)
 	}
 
-	if mc.Spec.HubAcceptsClient {
+	if isDecommissioning(mc) {
+		klog.Infof("ManagedCluster %s is decommissioning, skip accept", key.String())
+		return api.Done, 0, nil
+	}
+
+	if mc.Spec.HubAcceptsClient && !ctrl.rules.needApply(mc) {
 		klog.Infof("hubAcceptsClient already set for managed cluster %s", key.String())
 		return api.Done, 0, nil
 	}
@@ -76,19 +87,34 @@ func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, ti
 	// get csrlist
 	csrs := &certificatesv1.CertificateSigningRequestList{}
 	err = ctrl.client.List(csrs, &client.ListOptions{
-		LabelSelector: labels.Set{clusterLabel: key.Name}.AsSelector(),
+		LabelSelector: labels.Set{kube.ClusterNameLabel: key.Name}.AsSelector(),
 	})
 	if err != nil {
 		return api.Done, 0, fmt.Errorf("Get %s CertificateSigningRequestList failed:%+v", key.String(), err)
 	}
 
+	if mc.Spec.HubAcceptsClient {
+		return ctrl.applyRules(mc, csrs.Items)
+	}
+
+	if err = checkPreAuthorized(mc); err != nil {
+		klog.Warningf("Skip approving: %v", err)
+		return api.Done, 0, nil
+	}
+
 	if len(csrs.Items) == 0 {
 		klog.Warningf("Not found csr with %s, please check registration logic.", key.Name)
 		return api.Requeue, time.Second * 5, nil
 	}
+	authorized := false
 	for _, item := range csrs.Items {
+		if !isPreAuthorizedCSR(mc, &item) {
+			klog.Warningf("CSR %s requested by %s is not pre-authorized.", item.Name, item.Spec.Username)
+			continue
+		}
 		approved, denied := getCertApprovalCondition(&item.Status)
 		if approved {
+			authorized = true
 			klog.Warningf("CSR %s already approved.", item.Name)
 			continue
 		}
@@ -99,8 +125,18 @@ func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, ti
 		if err = ctrl.approveCSR(&item); err != nil {
 			return api.Done, 0, err
 		}
+		authorized = true
+	}
+	if PreAuthorizedOnly && !authorized {
+		klog.Warningf("Not found pre-authorized csr with %s.", key.Name)
+		return api.Requeue, time.Second * 5, nil
 	}
 
+	mc = mc.DeepCopy()
+	if ctrl.rules.needApply(mc) {
+		labels := ctrl.rules.apply(mc, csrs.Items)
+		klog.Infof("Accept rules set labels %v for ManagedCluster %s", labels, key.String())
+	}
 	mc.Spec.HubAcceptsClient = true
 	err = ctrl.client.Update(mc)
 	if err != nil {
@@ -110,6 +146,17 @@ func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, ti
 	return api.Done, 0, nil
 }
 
+// applyRules for cluster already accepted, such as ClusterClaims reported after joined
+func (ctrl *Controller) applyRules(mc *clusterapiv1.ManagedCluster, csrs []certificatesv1.CertificateSigningRequest) (api.NeedRequeue, time.Duration, error) {
+	mc = mc.DeepCopy()
+	labels := ctrl.rules.apply(mc, csrs)
+	if err := ctrl.client.Update(mc); err != nil {
+		return api.Done, 0, fmt.Errorf("Apply accept rules for ManagedCluster %s failed:%+v", mc.Name, err)
+	}
+	klog.Infof("Accept rules set labels %v for ManagedCluster %s", labels, mc.Name)
+	return api.Done, 0, nil
+}
+
 func (ctrl *Controller) approveCSR(csr *certificatesv1.CertificateSigningRequest) error {
 	if csr.Status.Conditions == nil {
 		csr.Status.Conditions = make([]certificatesv1.CertificateSigningRequestCondition, 0)
@@ -0,0 +1,46 @@
+package accept
+
+import (
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var (
+	// PreAuthorizedOnly only approve ManagedClusters pre-authorized such as by join-kit, default approve all
+	PreAuthorizedOnly = false
+	// PreAuthorizedUntilAnnotation RFC3339 time, cluster registered after it is not approved
+	PreAuthorizedUntilAnnotation = "clustermanager.io/pre-authorized-until"
+	// PreAuthorizedUserAnnotation only CSR requested by the user is approved, such as the bootstrap ServiceAccount
+	PreAuthorizedUserAnnotation = "clustermanager.io/pre-authorized-user"
+)
+
+// checkPreAuthorized returns error explains why cluster is not allowed to be approved
+func checkPreAuthorized(mc *clusterapiv1.ManagedCluster) error {
+	if !PreAuthorizedOnly {
+		return nil
+	}
+	until, ok := mc.Annotations[PreAuthorizedUntilAnnotation]
+	if !ok {
+		return fmt.Errorf("ManagedCluster %s is not pre-authorized", mc.Name)
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return fmt.Errorf("parse %s of ManagedCluster %s failed: %+v", PreAuthorizedUntilAnnotation, mc.Name, err)
+	}
+	if time.Now().After(t) {
+		return fmt.Errorf("pre-authorization of ManagedCluster %s expired at %s", mc.Name, until)
+	}
+	return nil
+}
+
+// isPreAuthorizedCSR CSR requested by other user is not approved when pre-authorized user set
+func isPreAuthorizedCSR(mc *clusterapiv1.ManagedCluster, csr *certificatesv1.CertificateSigningRequest) bool {
+	if !PreAuthorizedOnly {
+		return true
+	}
+	user, ok := mc.Annotations[PreAuthorizedUserAnnotation]
+	return !ok || user == csr.Spec.Username
+}
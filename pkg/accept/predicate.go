@@ -1,11 +1,14 @@
 package accept
 
 import (
+	"github.com/champly/clustermanager/pkg/lifecycle"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type predicate struct{}
+type predicate struct {
+	rules *Rules
+}
 
 func (p *predicate) Create(obj client.Object) bool {
 	return p.handler(obj)
@@ -28,5 +31,14 @@ func (p *predicate) handler(obj client.Object) bool {
 	if !ok {
 		return false
 	}
-	return !managedCluster.Spec.HubAcceptsClient
+	if isDecommissioning(managedCluster) {
+		return false
+	}
+	return !managedCluster.Spec.HubAcceptsClient || p.rules.needApply(managedCluster)
+}
+
+// isDecommissioning hubAcceptsClient is disabled by decommission, should not accept again
+func isDecommissioning(mc *clusterapiv1.ManagedCluster) bool {
+	_, ok := mc.Annotations[lifecycle.DecommissionAnnotation]
+	return ok || mc.DeletionTimestamp != nil
 }
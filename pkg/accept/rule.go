@@ -0,0 +1,191 @@
+package accept
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	rulesAppliedWithCSR    = "csr"
+	rulesAppliedWithClaims = "claims"
+)
+
+var (
+	// RulesFile yaml or json file contains Rules
+	RulesFile       = ""
+	ClusterSetLabel = "cluster.open-cluster-management.io/clusterset"
+	// RulesAppliedAnnotation ClusterClaims are reported after cluster joined, rules are applied again
+	// once claims appear if they were applied with csr only.
+	RulesAppliedAnnotation = "clustermanager.io/accept-rules-applied"
+	// RulesLabelsAnnotation label keys written by rules, later passes override them
+	RulesLabelsAnnotation = "clustermanager.io/accept-rules-labels"
+)
+
+// Rules labels set on ManagedCluster when accepted, all conditions in match support path.Match patterns.
+// Rules are applied in order and later rules override earlier ones, labels not written
+// by rules such as set by hand are kept. ManagedCluster taints are not available in the current cluster api.
+//
+//	rules:
+//	- match:
+//	    clusterName: prod-*
+//	    claims:
+//	      platform.open-cluster-management.io: AWS
+//	      region.open-cluster-management.io: us-*
+//	    csr:
+//	      username: system:open-cluster-management:prod-*
+//	  clusterSet: prod
+//	  labels:
+//	    env: prod
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Match      RuleMatch         `json:"match"`
+	ClusterSet string            `json:"clusterSet"`
+	Labels     map[string]string `json:"labels"`
+}
+
+type RuleMatch struct {
+	ClusterName string `json:"clusterName"`
+	// Claims ClusterClaim name -> value pattern
+	Claims map[string]string `json:"claims"`
+	CSR    *CSRMatch         `json:"csr"`
+}
+
+// CSRMatch any CSR of the cluster matches
+type CSRMatch struct {
+	Username string            `json:"username"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func LoadRules(file string) (*Rules, error) {
+	rules := &Rules{}
+	if file == "" {
+		return rules, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read accept rules file %s failed: %+v", file, err)
+	}
+	if err = yaml.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("parse accept rules file %s failed: %+v", file, err)
+	}
+	return rules, nil
+}
+
+// needApply rules are applied once with csr, and once more when ClusterClaims reported
+func (r *Rules) needApply(mc *clusterapiv1.ManagedCluster) bool {
+	if len(r.Rules) == 0 {
+		return false
+	}
+	switch mc.Annotations[RulesAppliedAnnotation] {
+	case rulesAppliedWithClaims:
+		return false
+	case rulesAppliedWithCSR:
+		return len(mc.Status.ClusterClaims) > 0
+	}
+	return true
+}
+
+// apply returns labels set on ManagedCluster
+func (r *Rules) apply(mc *clusterapiv1.ManagedCluster, csrs []certificatesv1.CertificateSigningRequest) map[string]string {
+	labels := map[string]string{}
+	for _, rule := range r.Rules {
+		if !rule.Match.matches(mc, csrs) {
+			continue
+		}
+		if rule.ClusterSet != "" {
+			labels[ClusterSetLabel] = rule.ClusterSet
+		}
+		for k, v := range rule.Labels {
+			labels[k] = v
+		}
+	}
+
+	if mc.Labels == nil {
+		mc.Labels = map[string]string{}
+	}
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	// labels written by rules before can be overwritten, others such as set by hand are kept
+	owned := map[string]bool{}
+	for _, k := range strings.Split(mc.Annotations[RulesLabelsAnnotation], ",") {
+		owned[k] = k != ""
+	}
+	applied := map[string]string{}
+	for k, v := range labels {
+		if _, ok := mc.Labels[k]; ok && !owned[k] {
+			continue
+		}
+		mc.Labels[k] = v
+		applied[k] = v
+		owned[k] = true
+	}
+	keys := []string{}
+	for k, ok := range owned {
+		if ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	mc.Annotations[RulesLabelsAnnotation] = strings.Join(keys, ",")
+	mc.Annotations[RulesAppliedAnnotation] = rulesAppliedWithCSR
+	if len(mc.Status.ClusterClaims) > 0 {
+		mc.Annotations[RulesAppliedAnnotation] = rulesAppliedWithClaims
+	}
+	return applied
+}
+
+func (m RuleMatch) matches(mc *clusterapiv1.ManagedCluster, csrs []certificatesv1.CertificateSigningRequest) bool {
+	if m.ClusterName != "" && !match(m.ClusterName, mc.Name) {
+		return false
+	}
+	for name, pattern := range m.Claims {
+		found := false
+		for _, claim := range mc.Status.ClusterClaims {
+			if claim.Name == name && match(pattern, claim.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.CSR == nil {
+		return true
+	}
+	for _, csr := range csrs {
+		if m.CSR.matches(csr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *CSRMatch) matches(csr certificatesv1.CertificateSigningRequest) bool {
+	if m.Username != "" && !match(m.Username, csr.Spec.Username) {
+		return false
+	}
+	for k, pattern := range m.Labels {
+		v, ok := csr.Labels[k]
+		if !ok || !match(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func match(pattern, s string) bool {
+	matched, err := path.Match(pattern, s)
+	return err == nil && matched
+}
@@ -0,0 +1,96 @@
+package accept
+
+import (
+	"testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestRulesApply(t *testing.T) {
+	rules := &Rules{Rules: []Rule{
+		{Match: RuleMatch{ClusterName: "*"}, ClusterSet: "default"},
+		{Match: RuleMatch{Claims: map[string]string{"region.open-cluster-management.io": "us-*"}}, ClusterSet: "prod", Labels: map[string]string{"env": "prod"}},
+		{Match: RuleMatch{CSR: &CSRMatch{Username: "system:serviceaccount:open-cluster-management:*"}}, Labels: map[string]string{"joined-by": "join-kit"}},
+	}}
+	csrs := []certificatesv1.CertificateSigningRequest{{
+		Spec: certificatesv1.CertificateSigningRequestSpec{Username: "system:serviceaccount:open-cluster-management:cluster-a-bootstrap"},
+	}}
+
+	mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:   "cluster-a",
+		Labels: map[string]string{"env": "staging"},
+	}}
+
+	// first pass before joined, no claims
+	if !rules.needApply(mc) {
+		t.Fatal("expected rules applied for new cluster")
+	}
+	rules.apply(mc, csrs)
+	expectLabels(t, mc, map[string]string{ClusterSetLabel: "default", "env": "staging", "joined-by": "join-kit"})
+	if mc.Annotations[RulesAppliedAnnotation] != rulesAppliedWithCSR {
+		t.Errorf("expected applied with csr, got %s", mc.Annotations[RulesAppliedAnnotation])
+	}
+	if rules.needApply(mc) {
+		t.Error("expected rules not applied again before claims reported")
+	}
+
+	// claims reported after joined, later rule overrides clusterset written by rules, env set by hand is kept
+	mc.Status.ClusterClaims = []clusterapiv1.ManagedClusterClaim{{Name: "region.open-cluster-management.io", Value: "us-east-1"}}
+	if !rules.needApply(mc) {
+		t.Fatal("expected rules applied again after claims reported")
+	}
+	rules.apply(mc, nil)
+	expectLabels(t, mc, map[string]string{ClusterSetLabel: "prod", "env": "staging", "joined-by": "join-kit"})
+	if mc.Annotations[RulesLabelsAnnotation] != "cluster.open-cluster-management.io/clusterset,joined-by" {
+		t.Errorf("unexpected rules labels annotation %s", mc.Annotations[RulesLabelsAnnotation])
+	}
+	if rules.needApply(mc) {
+		t.Error("expected rules not applied after claims applied")
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "prod-eks-1"}}
+	mc.Status.ClusterClaims = []clusterapiv1.ManagedClusterClaim{{Name: "platform.open-cluster-management.io", Value: "AWS"}}
+	csrs := []certificatesv1.CertificateSigningRequest{{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "infra"}},
+		Spec:       certificatesv1.CertificateSigningRequestSpec{Username: "system:open-cluster-management:prod-eks-1"},
+	}}
+
+	tests := []struct {
+		name  string
+		match RuleMatch
+		want  bool
+	}{
+		{"empty", RuleMatch{}, true},
+		{"name", RuleMatch{ClusterName: "prod-*"}, true},
+		{"name mismatch", RuleMatch{ClusterName: "dev-*"}, false},
+		{"claim", RuleMatch{Claims: map[string]string{"platform.open-cluster-management.io": "AWS"}}, true},
+		{"claim missing", RuleMatch{Claims: map[string]string{"region.open-cluster-management.io": "*"}}, false},
+		{"csr username", RuleMatch{CSR: &CSRMatch{Username: "system:open-cluster-management:prod-*"}}, true},
+		{"csr label", RuleMatch{CSR: &CSRMatch{Labels: map[string]string{"team": "infra"}}}, true},
+		{"csr label mismatch", RuleMatch{CSR: &CSRMatch{Labels: map[string]string{"team": "app"}}}, false},
+		{"all", RuleMatch{ClusterName: "prod-*", Claims: map[string]string{"platform.open-cluster-management.io": "A*"}, CSR: &CSRMatch{Labels: map[string]string{"team": "*"}}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.match.matches(mc, csrs); got != tt.want {
+			t.Errorf("%s: matches = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func expectLabels(t *testing.T, mc *clusterapiv1.ManagedCluster, want map[string]string) {
+	t.Helper()
+	if len(mc.Labels) != len(want) {
+		t.Errorf("labels = %v, want %v", mc.Labels, want)
+		return
+	}
+	for k, v := range want {
+		if mc.Labels[k] != v {
+			t.Errorf("labels = %v, want %v", mc.Labels, want)
+			return
+		}
+	}
+}
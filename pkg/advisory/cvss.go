@@ -0,0 +1,123 @@
+package advisory
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	cvss3Weights = map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	// cvss3ChangedPR privileges required weights when scope changed
+	cvss3ChangedPR = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+
+	cvss2Weights = map[string]map[string]float64{
+		"AV": {"L": 0.395, "A": 0.646, "N": 1.0},
+		"AC": {"H": 0.35, "M": 0.61, "L": 0.71},
+		"Au": {"M": 0.45, "S": 0.56, "N": 0.704},
+		"C":  {"N": 0, "P": 0.275, "C": 0.660},
+		"I":  {"N": 0, "P": 0.275, "C": 0.660},
+		"A":  {"N": 0, "P": 0.275, "C": 0.660},
+	}
+)
+
+// cvssBaseScore base score of CVSS v3.x vector such as CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+// or CVSS v2 vector such as AV:N/AC:L/Au:N/C:P/I:P/A:P
+func cvssBaseScore(vector string) (float64, error) {
+	if strings.HasPrefix(vector, "CVSS:3.") {
+		return cvss3BaseScore(vector)
+	}
+	if strings.HasPrefix(vector, "CVSS:") {
+		return 0, fmt.Errorf("unsupported cvss vector %s", vector)
+	}
+	return cvss2BaseScore(vector)
+}
+
+func cvss3BaseScore(vector string) (float64, error) {
+	metrics, err := parseCVSSMetrics(vector[strings.Index(vector, "/")+1:])
+	if err != nil {
+		return 0, err
+	}
+	w := map[string]float64{}
+	for name, weights := range cvss3Weights {
+		value, ok := weights[metrics[name]]
+		if !ok {
+			return 0, fmt.Errorf("invalid cvss vector %s: metric %s", vector, name)
+		}
+		w[name] = value
+	}
+	changed := false
+	switch metrics["S"] {
+	case "C":
+		changed = true
+		w["PR"] = cvss3ChangedPR[metrics["PR"]]
+	case "U":
+	default:
+		return 0, fmt.Errorf("invalid cvss vector %s: metric S", vector)
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if changed {
+		return cvss3Roundup(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return cvss3Roundup(math.Min(impact+exploitability, 10)), nil
+}
+
+// cvss3Roundup smallest one decimal number not less than input, defined in CVSS v3.1 appendix A
+func cvss3Roundup(f float64) float64 {
+	i := int64(math.Round(f * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+func cvss2BaseScore(vector string) (float64, error) {
+	metrics, err := parseCVSSMetrics(strings.Trim(vector, "()"))
+	if err != nil {
+		return 0, err
+	}
+	w := map[string]float64{}
+	for name, weights := range cvss2Weights {
+		value, ok := weights[metrics[name]]
+		if !ok {
+			return 0, fmt.Errorf("invalid cvss vector %s: metric %s", vector, name)
+		}
+		w[name] = value
+	}
+
+	impact := 10.41 * (1 - (1-w["C"])*(1-w["I"])*(1-w["A"]))
+	if impact == 0 {
+		return 0, nil
+	}
+	exploitability := 20 * w["AV"] * w["AC"] * w["Au"]
+	return math.Round((0.6*impact+0.4*exploitability-1.5)*1.176*10) / 10, nil
+}
+
+func parseCVSSMetrics(s string) (map[string]string, error) {
+	metrics := map[string]string{}
+	for _, part := range strings.Split(s, "/") {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid cvss metric %s", part)
+		}
+		metrics[kv[0]] = kv[1]
+	}
+	return metrics, nil
+}
@@ -0,0 +1,194 @@
+package advisory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/report"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// AdvisoryFile OSV json file or directory synced separately, matching is disabled when empty
+	AdvisoryFile = ""
+
+	reportLock  sync.Mutex
+	localReport = VulnerabilityReport{Clusters: []ClusterVulnerability{}}
+)
+
+type VulnerabilityReport struct {
+	Advisories int
+	MatchTime  time.Time
+	Clusters   []ClusterVulnerability
+}
+
+type ClusterVulnerability struct {
+	ClusterName string
+	// SeverityTotals severity -> count of findings
+	SeverityTotals map[string]int
+	// AffectedWorkloads count of distinct namespace/kind/name affected
+	AffectedWorkloads int
+	Findings          []Finding
+}
+
+type Finding struct {
+	resource.ImageReference
+	AdvisoryID string
+	Aliases    []string
+	Summary    string
+	Severity   string
+	Fixed      []string
+}
+
+// Refresh reload advisory file and match against cached image inventory,
+// the result is cached and exported as metrics.
+func Refresh() {
+	if AdvisoryFile == "" {
+		return
+	}
+	db, err := LoadDatabase(AdvisoryFile)
+	if err != nil {
+		klog.Errorf("load advisory database failed: %+v", err)
+		return
+	}
+	vr := Match(db, resource.GetAllCacheImageInventory())
+
+	reportLock.Lock()
+	localReport = vr
+	reportLock.Unlock()
+
+	updateMetrics(vr)
+}
+
+func GetVulnerabilityReport() VulnerabilityReport {
+	reportLock.Lock()
+	defer reportLock.Unlock()
+	return localReport
+}
+
+// Match find images whose repository is affected and tag or digest is listed in versions
+// or falls in SEMVER/ECOSYSTEM ranges.
+func Match(db *Database, list []resource.ClusterImageInventory) VulnerabilityReport {
+	vr := VulnerabilityReport{
+		Advisories: db.Advisories,
+		MatchTime:  time.Now(),
+		Clusters:   make([]ClusterVulnerability, 0, len(list)),
+	}
+	for _, inv := range list {
+		cv := ClusterVulnerability{
+			ClusterName:    inv.ClusterName,
+			SeverityTotals: map[string]int{},
+			Findings:       []Finding{},
+		}
+		for _, severity := range Severities {
+			cv.SeverityTotals[severity] = 0
+		}
+
+		workloads := map[string]struct{}{}
+		for _, ref := range inv.Images {
+			for _, e := range db.byRepository[report.NormalizeRepository(ref.Repository)] {
+				if !e.affected.matches(ref.Tag, ref.Digest) {
+					continue
+				}
+				finding := Finding{
+					ImageReference: ref,
+					AdvisoryID:     e.osv.ID,
+					Aliases:        e.osv.Aliases,
+					Summary:        e.osv.Summary,
+					Severity:       e.severity(),
+					Fixed:          e.affected.fixedVersions(),
+				}
+				cv.Findings = append(cv.Findings, finding)
+				cv.SeverityTotals[finding.Severity]++
+				workloads[ref.Namespace+"/"+ref.WorkloadKind+"/"+ref.WorkloadName] = struct{}{}
+			}
+		}
+		cv.AffectedWorkloads = len(workloads)
+		sort.SliceStable(cv.Findings, func(i, j int) bool {
+			return severityRank(cv.Findings[i].Severity) < severityRank(cv.Findings[j].Severity)
+		})
+		vr.Clusters = append(vr.Clusters, cv)
+	}
+	return vr
+}
+
+func (a OSVAffected) matches(tag, digest string) bool {
+	for _, v := range a.Versions {
+		if v != "" && (v == tag || v == digest) {
+			return true
+		}
+	}
+	if tag == "" {
+		return false
+	}
+	tagVersion, err := version.ParseGeneric(tag)
+	if err != nil {
+		return false
+	}
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+		if r.contains(tagVersion) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains evaluate events in order, each introduced opens a range closed by following fixed or last_affected
+func (r OSVRange) contains(v *version.Version) bool {
+	var introduced *version.Version
+	open := false
+	for _, e := range r.Events {
+		switch {
+		case e.Introduced != "":
+			open = true
+			introduced = nil
+			if e.Introduced != "0" {
+				if introduced, _ = version.ParseGeneric(e.Introduced); introduced == nil {
+					open = false
+				}
+			}
+		case open && e.Fixed != "":
+			open = false
+			if fixed, err := version.ParseGeneric(e.Fixed); err == nil && atLeast(v, introduced) && v.LessThan(fixed) {
+				return true
+			}
+		case open && e.LastAffected != "":
+			open = false
+			if last, err := version.ParseGeneric(e.LastAffected); err == nil && atLeast(v, introduced) && !last.LessThan(v) {
+				return true
+			}
+		}
+	}
+	return open && atLeast(v, introduced)
+}
+
+func atLeast(v, introduced *version.Version) bool {
+	return introduced == nil || v.AtLeast(introduced)
+}
+
+func (a OSVAffected) fixedVersions() []string {
+	fixed := []string{}
+	for _, r := range a.Ranges {
+		for _, e := range r.Events {
+			if e.Fixed != "" {
+				fixed = append(fixed, e.Fixed)
+			}
+		}
+	}
+	return fixed
+}
+
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities)
+}
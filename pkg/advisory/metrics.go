@@ -0,0 +1,34 @@
+package advisory
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	vulnerabilityFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "clustermanager",
+		Name:      "image_vulnerabilities",
+		Help:      "Count of image vulnerability findings per cluster and severity.",
+	}, []string{"cluster", "severity"})
+	vulnerableWorkloads = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "clustermanager",
+		Name:      "vulnerable_workloads",
+		Help:      "Count of workloads running at least one vulnerable image per cluster.",
+	}, []string{"cluster"})
+)
+
+func init() {
+	prometheus.MustRegister(vulnerabilityFindings, vulnerableWorkloads)
+}
+
+func updateMetrics(vr VulnerabilityReport) {
+	// clusters removed from fleet should not keep stale series
+	vulnerabilityFindings.Reset()
+	vulnerableWorkloads.Reset()
+	for _, cv := range vr.Clusters {
+		for severity, n := range cv.SeverityTotals {
+			vulnerabilityFindings.WithLabelValues(cv.ClusterName, severity).Set(float64(n))
+		}
+		vulnerableWorkloads.WithLabelValues(cv.ClusterName).Set(float64(cv.AffectedWorkloads))
+	}
+}
@@ -0,0 +1,197 @@
+package advisory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/champly/clustermanager/pkg/report"
+)
+
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// Severities ordered from the most severe
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityUnknown}
+
+// OSV subset of https://ossf.github.io/osv-schema used for image matching,
+// affected package name is image repository, versions are tags or digests.
+type OSV struct {
+	ID               string                 `json:"id"`
+	Summary          string                 `json:"summary"`
+	Aliases          []string               `json:"aliases"`
+	Severity         []OSVSeverity          `json:"severity"`
+	Affected         []OSVAffected          `json:"affected"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific"`
+}
+
+type OSVSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type OSVAffected struct {
+	Package           OSVPackage             `json:"package"`
+	Ranges            []OSVRange             `json:"ranges"`
+	Versions          []string               `json:"versions"`
+	EcosystemSpecific map[string]interface{} `json:"ecosystem_specific"`
+}
+
+type OSVPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl"`
+}
+
+type OSVRange struct {
+	Type   string     `json:"type"`
+	Events []OSVEvent `json:"events"`
+}
+
+type OSVEvent struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+}
+
+// Database advisories indexed by normalized image repository
+type Database struct {
+	Advisories   int
+	byRepository map[string][]entry
+}
+
+type entry struct {
+	osv      *OSV
+	affected OSVAffected
+}
+
+// LoadDatabase load OSV advisories from a json file or every json file in directory,
+// file contains one advisory or an array of advisories.
+func LoadDatabase(path string) (*Database, error) {
+	if path == "" {
+		return nil, fmt.Errorf("advisory file not configured")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat advisory file %s failed: %+v", path, err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, fmt.Errorf("list advisory dir %s failed: %+v", path, err)
+		}
+	}
+
+	db := &Database{byRepository: map[string][]entry{}}
+	for _, file := range files {
+		list, err := readOSVFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			db.add(&list[i])
+		}
+	}
+	return db, nil
+}
+
+func readOSVFile(file string) ([]OSV, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read advisory file %s failed: %+v", file, err)
+	}
+	list := []OSV{}
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &list)
+	} else {
+		osv := OSV{}
+		err = json.Unmarshal(data, &osv)
+		list = append(list, osv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse advisory file %s failed: %+v", file, err)
+	}
+	return list, nil
+}
+
+func (db *Database) add(osv *OSV) {
+	db.Advisories++
+	for _, affected := range osv.Affected {
+		repository := affectedRepository(affected.Package)
+		if repository == "" {
+			continue
+		}
+		repository = report.NormalizeRepository(repository)
+		db.byRepository[repository] = append(db.byRepository[repository], entry{osv: osv, affected: affected})
+	}
+}
+
+// affectedRepository image repository from package name or purl such as
+// pkg:docker/library/nginx@1.21 and pkg:oci/nginx?repository_url=docker.io/library/nginx
+func affectedRepository(pkg OSVPackage) string {
+	if pkg.Purl == "" {
+		return pkg.Name
+	}
+	purl := pkg.Purl
+	qualifiers := ""
+	if i := strings.Index(purl, "?"); i >= 0 {
+		purl, qualifiers = purl[:i], purl[i+1:]
+	}
+	if i := strings.Index(purl, "@"); i >= 0 {
+		purl = purl[:i]
+	}
+	switch {
+	case strings.HasPrefix(purl, "pkg:docker/"):
+		return strings.TrimPrefix(purl, "pkg:docker/")
+	case strings.HasPrefix(purl, "pkg:oci/"):
+		if values, err := url.ParseQuery(qualifiers); err == nil && values.Get("repository_url") != "" {
+			return values.Get("repository_url")
+		}
+		return strings.TrimPrefix(purl, "pkg:oci/")
+	}
+	return pkg.Name
+}
+
+// severity resolve order: affected ecosystem_specific, database_specific, CVSS base score computed from vector
+func (e entry) severity() string {
+	for _, m := range []map[string]interface{}{e.affected.EcosystemSpecific, e.osv.DatabaseSpecific} {
+		if s, ok := m["severity"].(string); ok && s != "" {
+			s = strings.ToUpper(s)
+			if s == "MODERATE" {
+				return SeverityMedium
+			}
+			return s
+		}
+	}
+	for _, s := range e.osv.Severity {
+		if score, err := cvssBaseScore(s.Score); err == nil {
+			return cvssSeverity(score)
+		}
+	}
+	return SeverityUnknown
+}
+
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
@@ -0,0 +1,118 @@
+package advisory
+
+import (
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+func TestCVSSBaseScore(t *testing.T) {
+	tests := []struct {
+		vector string
+		score  float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10.0},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1},
+		{"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8},
+		{"CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", 7.5},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:L/I:N/A:N", 5.3},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0},
+		{"AV:N/AC:L/Au:N/C:P/I:P/A:P", 7.5},
+		{"AV:N/AC:M/Au:N/C:N/I:P/A:N", 4.3},
+		{"AV:N/AC:L/Au:N/C:C/I:C/A:C", 10.0},
+	}
+	for _, tt := range tests {
+		score, err := cvssBaseScore(tt.vector)
+		if err != nil {
+			t.Errorf("cvssBaseScore(%s) error: %v", tt.vector, err)
+			continue
+		}
+		if score != tt.score {
+			t.Errorf("cvssBaseScore(%s) = %v, want %v", tt.vector, score, tt.score)
+		}
+	}
+
+	for _, vector := range []string{"CVSS:3.1/AV:N/AC:L", "CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", "9.8"} {
+		if _, err := cvssBaseScore(vector); err == nil {
+			t.Errorf("cvssBaseScore(%s) expected error", vector)
+		}
+	}
+}
+
+func TestSeverityOfRealRecord(t *testing.T) {
+	db, err := LoadDatabase("testdata/GHSA-jfh8-c2jp-5v3q.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []entry{}
+	for _, list := range db.byRepository {
+		entries = append(entries, list...)
+	}
+	if db.Advisories != 1 || len(entries) != 1 {
+		t.Fatalf("expected 1 advisory with 1 affected, got %d advisories %d entries", db.Advisories, len(entries))
+	}
+
+	e := entries[0]
+	if s := e.severity(); s != SeverityCritical {
+		t.Errorf("severity from database_specific = %s, want %s", s, SeverityCritical)
+	}
+	// most records carry only the CVSS vector
+	e.osv.DatabaseSpecific = nil
+	if s := e.severity(); s != SeverityCritical {
+		t.Errorf("severity from cvss vector = %s, want %s", s, SeverityCritical)
+	}
+	e.osv.Severity = []OSVSeverity{{Type: "CVSS_V3", Score: "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N"}}
+	if s := e.severity(); s != SeverityMedium {
+		t.Errorf("severity from cvss vector = %s, want %s", s, SeverityMedium)
+	}
+	e.osv.Severity = nil
+	if s := e.severity(); s != SeverityUnknown {
+		t.Errorf("severity without score = %s, want %s", s, SeverityUnknown)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	db := &Database{byRepository: map[string][]entry{}}
+	db.add(&OSV{
+		ID:       "TEST-0001",
+		Severity: []OSVSeverity{{Type: "CVSS_V3", Score: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+		Affected: []OSVAffected{{
+			Package: OSVPackage{Purl: "pkg:docker/library/nginx"},
+			Ranges: []OSVRange{{
+				Type:   "SEMVER",
+				Events: []OSVEvent{{Introduced: "1.20.0"}, {Fixed: "1.21.5"}},
+			}},
+			Versions: []string{"sha256:abc"},
+		}},
+	})
+
+	ref := func(name, image, tag, digest string) resource.ImageReference {
+		return resource.ImageReference{Namespace: "default", WorkloadKind: "Deployment", WorkloadName: name, Image: image, Repository: image, Tag: tag, Digest: digest}
+	}
+	vr := Match(db, []resource.ClusterImageInventory{{
+		ClusterName: "cluster-a",
+		Images: []resource.ImageReference{
+			ref("affected", "nginx", "1.21.1", ""),
+			ref("fixed", "docker.io/library/nginx", "1.21.5", ""),
+			ref("digest", "nginx", "latest", "sha256:abc"),
+			ref("other", "redis", "1.21.1", ""),
+		},
+	}})
+
+	if len(vr.Clusters) != 1 {
+		t.Fatalf("expected 1 cluster, got %d", len(vr.Clusters))
+	}
+	cv := vr.Clusters[0]
+	if len(cv.Findings) != 2 || cv.AffectedWorkloads != 2 {
+		t.Fatalf("expected 2 findings in 2 workloads, got %+v", cv)
+	}
+	for _, f := range cv.Findings {
+		if f.WorkloadName != "affected" && f.WorkloadName != "digest" {
+			t.Errorf("unexpected finding of workload %s", f.WorkloadName)
+		}
+	}
+	if cv.SeverityTotals[SeverityCritical] != 2 {
+		t.Errorf("expected 2 critical findings, got %v", cv.SeverityTotals)
+	}
+}
@@ -0,0 +1,107 @@
+package autolabel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestDeriveLabels(t *testing.T) {
+	mapping := &Mapping{Labels: append(DefaultMapping.Labels,
+		DerivedLabel{Key: "platform", Fact: FactDistribution, Values: map[string]string{"eks": "aws-eks"}},
+		DerivedLabel{Key: "clustermanager.io/k8s-version", Fact: FactKubernetesVersion},
+		DerivedLabel{Key: "clustermanager.io/zone", Fact: FactNodeLabel, NodeLabel: "topology.kubernetes.io/zone"},
+		DerivedLabel{Key: "clustermanager.io/pool", Fact: FactNodeLabel, NodeLabel: "pool"},
+	)}
+
+	cs := resource.ClusterStatus{
+		KubernetesVersion: "v1.23.4+k3s1",
+		Distribution:      resource.DistributionInfo{Distribution: resource.DistributionEKS, CloudProvider: "aws", Region: "us-east-1"},
+		Capacity:          corev1.ResourceList{"nvidia.com/gpu": apiresource.MustParse("2")},
+		Nodes: []resource.NodeInfo{
+			{Architecture: "amd64", Labels: map[string]string{"topology.kubernetes.io/zone": "us-east-1a", "pool": "a"}},
+			{Architecture: "arm64", Labels: map[string]string{"topology.kubernetes.io/zone": "us-east-1a", "pool": "b"}},
+		},
+	}
+	want := map[string]string{
+		"clustermanager.io/k8s-minor":      "1.23",
+		"clustermanager.io/platform":       "eks",
+		"clustermanager.io/cloud-provider": "aws",
+		"clustermanager.io/region":         "us-east-1",
+		"clustermanager.io/gpu":            "true",
+		"clustermanager.io/arch":           "multi",
+		"platform":                         "aws-eks",
+		"clustermanager.io/k8s-version":    "v1.23.4_k3s1",
+		"clustermanager.io/zone":           "us-east-1a",
+	}
+	expectMap(t, mapping.deriveLabels(cs), want)
+
+	// unknown facts are absent
+	expectMap(t, DefaultMapping.deriveLabels(resource.ClusterStatus{
+		Distribution: resource.DistributionInfo{Distribution: resource.DistributionUnknown},
+	}), map[string]string{})
+}
+
+func TestToLabelValue(t *testing.T) {
+	tests := map[string]string{
+		"1.23":         "1.23",
+		"v1.23.4+k3s1": "v1.23.4_k3s1",
+		"-leading.":    "leading",
+		"a b/c":        "a_b_c",
+		"":             "",
+		"__":           "",
+	}
+	tests[strings.Repeat("a", 70)] = strings.Repeat("a", 63)
+	for in, want := range tests {
+		if got := toLabelValue(in); got != want {
+			t.Errorf("toLabelValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUpdateLabels(t *testing.T) {
+	mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{
+			"platform":                    "by-hand",
+			"clustermanager.io/gpu":       "true",
+			"clustermanager.io/k8s-minor": "1.22",
+		},
+		Annotations: map[string]string{ManagedLabelsAnnotation: "clustermanager.io/gpu,clustermanager.io/k8s-minor"},
+	}}
+
+	// gpu written before and unknown now is removed, platform never written by autolabel is kept
+	if !updateLabels(mc, map[string]string{"clustermanager.io/k8s-minor": "1.23", "clustermanager.io/arch": "amd64"}) {
+		t.Fatal("expected labels changed")
+	}
+	expectMap(t, mc.Labels, map[string]string{
+		"platform":                    "by-hand",
+		"clustermanager.io/k8s-minor": "1.23",
+		"clustermanager.io/arch":      "amd64",
+	})
+	if got := mc.Annotations[ManagedLabelsAnnotation]; got != "clustermanager.io/arch,clustermanager.io/k8s-minor" {
+		t.Errorf("managed labels annotation = %s", got)
+	}
+
+	if updateLabels(mc, map[string]string{"clustermanager.io/k8s-minor": "1.23", "clustermanager.io/arch": "amd64"}) {
+		t.Error("expected labels not changed")
+	}
+}
+
+func expectMap(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+		return
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got %v, want %v", got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package autolabel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/handler"
+	"github.com/symcn/pkg/clustermanager/workqueue"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var (
+	// ManagedLabelsAnnotation keys written last time, labels removed from mapping are cleaned up with it
+	ManagedLabelsAnnotation = "clustermanager.io/auto-labels"
+	// ResyncInterval facts are collected periodically, which don't trigger ManagedCluster events
+	ResyncInterval = time.Second * 60
+)
+
+type Controller struct {
+	ctx     context.Context
+	client  api.MingleClient
+	queue   api.WorkQueue
+	mapping *Mapping
+}
+
+func New(ctx context.Context) (*Controller, error) {
+	mapping, err := LoadMapping(MappingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &Controller{
+		ctx:     ctx,
+		client:  kube.ManagerPlaneClusterClient,
+		mapping: mapping,
+	}
+
+	queue, err := workqueue.Complted(workqueue.NewQueueConfig(ctrl)).NewQueue()
+	if err != nil {
+		return nil, fmt.Errorf("Build workqueue failed:%+v", err)
+	}
+	ctrl.queue = queue
+
+	err = ctrl.client.AddResourceEventHandler(
+		&clusterapiv1.ManagedCluster{},
+		handler.NewResourceEventHandler(
+			ctrl.queue,
+			handler.NewDefaultTransformNamespacedNameEventHandler(),
+			&predicate{},
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("AddResourceEventHandler with managedcluster failed:%+v", err)
+	}
+
+	return ctrl, nil
+}
+
+func (ctrl *Controller) Start() error {
+	return ctrl.queue.Start(ctrl.ctx)
+}
+
+func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, time.Duration, error) {
+	mc := &clusterapiv1.ManagedCluster{}
+	err := ctrl.client.Get(key, mc)
+	if apierrors.IsNotFound(err) {
+		return api.Done, 0, nil
+	}
+	if err != nil {
+		return api.Done, 0, fmt.Errorf("Get ManagedCluster %s failed:%+v", key.String(), err)
+	}
+
+	// labels are kept until facts collected, collect may not run yet after restart
+	cs, ok := resource.GetCacheClusterStatusWithClusterName(key.Name)
+	if !ok {
+		return api.Requeue, ResyncInterval, nil
+	}
+
+	mc = mc.DeepCopy()
+	if !updateLabels(mc, ctrl.mapping.deriveLabels(cs)) {
+		return api.Requeue, ResyncInterval, nil
+	}
+	if err = ctrl.client.Update(mc); err != nil {
+		return api.Done, 0, fmt.Errorf("Set auto labels for ManagedCluster %s failed:%+v", key.String(), err)
+	}
+	klog.Infof("Set auto labels %s for ManagedCluster %s", mc.Annotations[ManagedLabelsAnnotation], key.String())
+	return api.Requeue, ResyncInterval, nil
+}
+
+// updateLabels set derived labels and remove stale ones written before, labels never written by
+// autolabel such as set by hand are kept, returns true if changed
+func updateLabels(mc *clusterapiv1.ManagedCluster, derived map[string]string) bool {
+	if mc.Labels == nil {
+		mc.Labels = map[string]string{}
+	}
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+
+	changed := false
+	stale := map[string]bool{}
+	for _, key := range strings.Split(mc.Annotations[ManagedLabelsAnnotation], ",") {
+		if key != "" {
+			stale[key] = true
+		}
+	}
+	for key, value := range derived {
+		delete(stale, key)
+		if mc.Labels[key] != value {
+			mc.Labels[key] = value
+			changed = true
+		}
+	}
+	for key := range stale {
+		if _, ok := mc.Labels[key]; ok {
+			delete(mc.Labels, key)
+			changed = true
+		}
+	}
+
+	keys := make([]string, 0, len(derived))
+	for key := range derived {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	managed := strings.Join(keys, ",")
+	if mc.Annotations[ManagedLabelsAnnotation] != managed {
+		mc.Annotations[ManagedLabelsAnnotation] = managed
+		changed = true
+	}
+	return changed
+}
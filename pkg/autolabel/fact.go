@@ -0,0 +1,201 @@
+package autolabel
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/report"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FactKubernetesVersion = "kubernetesVersion"
+	FactKubernetesMinor   = "kubernetesMinor"
+	FactDistribution      = "distribution"
+	FactCloudProvider     = "cloudProvider"
+	FactRegion            = "region"
+	FactArch              = "arch"
+	FactGPU               = "gpu"
+	// FactNodeLabel value of NodeLabel shared by all nodes
+	FactNodeLabel = "nodeLabel"
+
+	// archMulti nodes with different architectures
+	archMulti = "multi"
+)
+
+var (
+	// MappingFile yaml or json file contains Mapping, DefaultMapping is used when empty
+	MappingFile = ""
+
+	DefaultMapping = Mapping{
+		Labels: []DerivedLabel{
+			{Key: "clustermanager.io/k8s-minor", Fact: FactKubernetesMinor},
+			{Key: "clustermanager.io/platform", Fact: FactDistribution},
+			{Key: "clustermanager.io/cloud-provider", Fact: FactCloudProvider},
+			{Key: "clustermanager.io/region", Fact: FactRegion},
+			{Key: "clustermanager.io/gpu", Fact: FactGPU},
+			{Key: "clustermanager.io/arch", Fact: FactArch},
+		},
+	}
+
+	// facts returns false when fact is unknown, the label is removed
+	facts = map[string]func(cs resource.ClusterStatus, dl DerivedLabel) (string, bool){
+		FactKubernetesVersion: kubernetesVersionFact,
+		FactKubernetesMinor:   kubernetesMinorFact,
+		FactDistribution:      distributionFact,
+		FactCloudProvider:     cloudProviderFact,
+		FactRegion:            regionFact,
+		FactArch:              archFact,
+		FactGPU:               gpuFact,
+		FactNodeLabel:         nodeLabelFact,
+	}
+
+	invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Mapping derived labels written on ManagedCluster from collected facts
+//
+//	labels:
+//	- key: clustermanager.io/k8s-minor
+//	  fact: kubernetesMinor
+//	- key: platform
+//	  fact: distribution
+//	  values: {eks: aws-eks}
+//	- key: clustermanager.io/zone
+//	  fact: nodeLabel
+//	  nodeLabel: topology.kubernetes.io/zone
+type Mapping struct {
+	Labels []DerivedLabel `json:"labels"`
+}
+
+type DerivedLabel struct {
+	Key  string `json:"key"`
+	Fact string `json:"fact"`
+	// NodeLabel used by nodeLabel fact
+	NodeLabel string `json:"nodeLabel"`
+	// Values optional fact value -> label value
+	Values map[string]string `json:"values"`
+}
+
+func LoadMapping(file string) (*Mapping, error) {
+	if file == "" {
+		return &DefaultMapping, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read auto label mapping file %s failed: %+v", file, err)
+	}
+	mapping := &Mapping{}
+	if err = yaml.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("parse auto label mapping file %s failed: %+v", file, err)
+	}
+	for _, dl := range mapping.Labels {
+		if errs := validation.IsQualifiedName(dl.Key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid auto label key %s: %v", dl.Key, errs)
+		}
+		if _, ok := facts[dl.Fact]; !ok {
+			return nil, fmt.Errorf("auto label %s with unknown fact %s", dl.Key, dl.Fact)
+		}
+		if dl.Fact == FactNodeLabel && dl.NodeLabel == "" {
+			return nil, fmt.Errorf("auto label %s with fact nodeLabel requires nodeLabel", dl.Key)
+		}
+	}
+	return mapping, nil
+}
+
+// deriveLabels label key -> value, keys with unknown fact are absent
+func (m *Mapping) deriveLabels(cs resource.ClusterStatus) map[string]string {
+	labels := map[string]string{}
+	for _, dl := range m.Labels {
+		value, ok := facts[dl.Fact](cs, dl)
+		if !ok {
+			continue
+		}
+		if v, ok := dl.Values[value]; ok {
+			value = v
+		}
+		if value = toLabelValue(value); value != "" {
+			labels[dl.Key] = value
+		}
+	}
+	return labels
+}
+
+func kubernetesVersionFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	return cs.KubernetesVersion, cs.KubernetesVersion != ""
+}
+
+func kubernetesMinorFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	v, err := version.ParseGeneric(cs.KubernetesVersion)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d.%d", v.Major(), v.Minor()), true
+}
+
+func distributionFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	d := cs.Distribution.Distribution
+	return d, d != "" && d != resource.DistributionUnknown
+}
+
+func cloudProviderFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	return cs.Distribution.CloudProvider, cs.Distribution.CloudProvider != ""
+}
+
+func regionFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	return cs.Distribution.Region, cs.Distribution.Region != ""
+}
+
+func archFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	arch := ""
+	for _, node := range cs.Nodes {
+		if arch != "" && arch != node.Architecture {
+			return archMulti, true
+		}
+		arch = node.Architecture
+	}
+	return arch, arch != ""
+}
+
+func gpuFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	if len(cs.Nodes) == 0 {
+		return "", false
+	}
+	for _, name := range report.GPUResourceNames {
+		if q, ok := cs.Capacity[corev1.ResourceName(name)]; ok && !q.IsZero() {
+			return "true", true
+		}
+	}
+	return "false", true
+}
+
+func nodeLabelFact(cs resource.ClusterStatus, dl DerivedLabel) (string, bool) {
+	value := ""
+	for i, node := range cs.Nodes {
+		v, ok := node.Labels[dl.NodeLabel]
+		if !ok || (i > 0 && v != value) {
+			return "", false
+		}
+		value = v
+	}
+	return value, value != ""
+}
+
+// toLabelValue replace invalid characters such as + in v1.23.4+k3s1, empty if still invalid
+func toLabelValue(s string) string {
+	s = invalidLabelValueChars.ReplaceAllString(s, "_")
+	if len(s) > validation.LabelValueMaxLength {
+		s = s[:validation.LabelValueMaxLength]
+	}
+	s = strings.Trim(s, "._-")
+	if len(validation.IsValidLabelValue(s)) > 0 {
+		return ""
+	}
+	return s
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/champly/clustermanager/pkg/advisory"
 	"github.com/champly/clustermanager/pkg/collect/resource"
 	"github.com/champly/clustermanager/pkg/kube"
 	clustetgatewayv1aplpha1 "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/v1alpha1"
 	"github.com/symcn/api"
 	symcnClient "github.com/symcn/pkg/clustermanager/client"
 	"github.com/symcn/pkg/clustermanager/configuration"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
@@ -35,6 +37,7 @@ func New(ctx context.Context) (*Controller, error) {
 	clustetgatewayv1aplpha1.AddToScheme(scheme)
 	clientgoscheme.AddToScheme(scheme)
 	workapiv1.AddToScheme(scheme)
+	apiextensionsv1.AddToScheme(scheme)
 
 	dynamicClient := dynamic.NewForConfigOrDie(kube.ManagerPlaneClusterClient.GetKubeRestConfig())
 	ccm := configuration.NewClusterCfgManagerWithGateway(dynamicClient, kube.ManagerPlaneClusterClient.GetClusterCfgInfo())
@@ -76,8 +79,31 @@ END:
 
 func (ctrl *Controller) collect() error {
 	for _, cli := range ctrl.GetAll() {
-		resource.CollectClusterStatus(cli)
-		resource.CollectDeploymentStatus(cli)
+		// pods listed once per cluster and shared, nil when list failed
+		pods, err := resource.ListPods(cli)
+		if err != nil {
+			klog.Warning(err)
+		}
+		resource.CollectClusterStatus(cli, pods)
+		resource.CollectDeploymentStatus(cli, pods)
+		resource.CollectNamespaceStatus(cli, pods)
+		resource.CollectClusterHealth(cli)
+		resource.CollectAPIInventory(cli)
+		resource.CollectImageInventory(cli, pods)
+		resource.CollectSecurityPosture(cli, pods)
+		resource.CollectRBACExposure(cli)
+		resource.CollectCertificates(cli)
 	}
+	// clusters removed while collecting may have put results after their cache cleared
+	resource.PruneCache(ctrl.clusterNames())
+	advisory.Refresh()
 	return nil
 }
+
+func (ctrl *Controller) clusterNames() []string {
+	names := []string{}
+	for _, cli := range ctrl.GetAll() {
+		names = append(names, cli.GetClusterCfgInfo().GetName())
+	}
+	return names
+}
@@ -0,0 +1,278 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/symcn/api"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	apiInventoryLock       sync.Mutex
+	localCacheAPIInventory = map[string]APIInventory{}
+)
+
+type APIInventory struct {
+	ClusterName string
+	Groups      []APIGroupInfo
+	CRDs        []CRDInfo
+	// CRDsCollected false when list CRDs failed, CRDs is empty but unknown
+	CRDsCollected      bool
+	MutatingWebhooks   []WebhookInfo
+	ValidatingWebhooks []WebhookInfo
+}
+
+type APIGroupInfo struct {
+	// Name empty is the core group
+	Name             string
+	PreferredVersion string
+	Versions         []APIVersionInfo
+}
+
+type APIVersionInfo struct {
+	Version   string
+	Resources []string
+}
+
+type CRDInfo struct {
+	Name           string
+	Group          string
+	Kind           string
+	Scope          string
+	Versions       []CRDVersion
+	StorageVersion string
+	// StoredVersions versions ever persisted in etcd, from status
+	StoredVersions []string
+	Established    bool
+}
+
+type CRDVersion struct {
+	Name       string
+	Served     bool
+	Storage    bool
+	Deprecated bool
+}
+
+type WebhookInfo struct {
+	Configuration  string
+	Name           string
+	FailurePolicy  string
+	SideEffects    string
+	TimeoutSeconds int32
+	// Service namespace/name:port or url of the webhook
+	Service string
+}
+
+// CollectAPIInventory record served api groups and resources from discovery,
+// installed CRDs and admission webhook configurations.
+func CollectAPIInventory(cli api.MingleProxyClient) {
+	inventory := APIInventory{
+		ClusterName:        cli.GetClusterCfgInfo().GetName(),
+		Groups:             getAPIGroups(cli),
+		CRDs:               []CRDInfo{},
+		MutatingWebhooks:   getMutatingWebhooks(cli),
+		ValidatingWebhooks: getValidatingWebhooks(cli),
+	}
+	if crds, err := getCRDs(cli); err != nil {
+		klog.Warningf("failed to list crds: %v", err)
+	} else {
+		inventory.CRDs = crds
+		inventory.CRDsCollected = true
+	}
+	putCacheAPIInventory(inventory.ClusterName, inventory)
+
+	data, _ := json.Marshal(inventory)
+	klog.V(4).Infof("get api inventory:\n%s", string(data))
+}
+
+func getAPIGroups(cli api.MingleProxyClient) []APIGroupInfo {
+	// partial result is returned when some aggregated apis are unavailable
+	groups, resources, err := cli.GetKubeInterface().Discovery().ServerGroupsAndResources()
+	if err != nil {
+		klog.Warningf("failed to discover api resources: %v", err)
+	}
+
+	versionResources := map[string][]string{}
+	for _, list := range resources {
+		names := []string{}
+		for _, r := range list.APIResources {
+			// skip subresources such as pods/status
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			names = append(names, r.Name)
+		}
+		sort.Strings(names)
+		versionResources[list.GroupVersion] = names
+	}
+
+	result := make([]APIGroupInfo, 0, len(groups))
+	for _, g := range groups {
+		info := APIGroupInfo{
+			Name:             g.Name,
+			PreferredVersion: g.PreferredVersion.Version,
+			Versions:         make([]APIVersionInfo, 0, len(g.Versions)),
+		}
+		for _, v := range g.Versions {
+			info.Versions = append(info.Versions, APIVersionInfo{
+				Version:   v.Version,
+				Resources: versionResources[v.GroupVersion],
+			})
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func getCRDs(cli api.MingleProxyClient) ([]CRDInfo, error) {
+	list := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), list); err != nil {
+		return nil, err
+	}
+
+	result := make([]CRDInfo, 0, len(list.Items))
+	for _, crd := range list.Items {
+		info := CRDInfo{
+			Name:           crd.Name,
+			Group:          crd.Spec.Group,
+			Kind:           crd.Spec.Names.Kind,
+			Scope:          string(crd.Spec.Scope),
+			Versions:       make([]CRDVersion, 0, len(crd.Spec.Versions)),
+			StoredVersions: crd.Status.StoredVersions,
+		}
+		for _, v := range crd.Spec.Versions {
+			info.Versions = append(info.Versions, CRDVersion{
+				Name:       v.Name,
+				Served:     v.Served,
+				Storage:    v.Storage,
+				Deprecated: v.Deprecated,
+			})
+			if v.Storage {
+				info.StorageVersion = v.Name
+			}
+		}
+		for _, c := range crd.Status.Conditions {
+			if c.Type == apiextensionsv1.Established {
+				info.Established = c.Status == apiextensionsv1.ConditionTrue
+			}
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func getMutatingWebhooks(cli api.MingleProxyClient) []WebhookInfo {
+	list := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), list); err != nil {
+		klog.Warningf("failed to list mutating webhook configurations: %v", err)
+		return []WebhookInfo{}
+	}
+
+	result := []WebhookInfo{}
+	for _, cfg := range list.Items {
+		for _, wh := range cfg.Webhooks {
+			result = append(result, buildWebhookInfo(cfg.Name, wh.Name, wh.FailurePolicy, wh.SideEffects, wh.TimeoutSeconds, wh.ClientConfig))
+		}
+	}
+	return result
+}
+
+func getValidatingWebhooks(cli api.MingleProxyClient) []WebhookInfo {
+	list := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), list); err != nil {
+		klog.Warningf("failed to list validating webhook configurations: %v", err)
+		return []WebhookInfo{}
+	}
+
+	result := []WebhookInfo{}
+	for _, cfg := range list.Items {
+		for _, wh := range cfg.Webhooks {
+			result = append(result, buildWebhookInfo(cfg.Name, wh.Name, wh.FailurePolicy, wh.SideEffects, wh.TimeoutSeconds, wh.ClientConfig))
+		}
+	}
+	return result
+}
+
+func buildWebhookInfo(configuration, name string, failurePolicy *admissionregistrationv1.FailurePolicyType, sideEffects *admissionregistrationv1.SideEffectClass, timeout *int32, cc admissionregistrationv1.WebhookClientConfig) WebhookInfo {
+	info := WebhookInfo{
+		Configuration: configuration,
+		Name:          name,
+		// defaults of admissionregistration/v1
+		FailurePolicy:  string(admissionregistrationv1.Fail),
+		TimeoutSeconds: 10,
+	}
+	if failurePolicy != nil {
+		info.FailurePolicy = string(*failurePolicy)
+	}
+	if sideEffects != nil {
+		info.SideEffects = string(*sideEffects)
+	}
+	if timeout != nil {
+		info.TimeoutSeconds = *timeout
+	}
+	switch {
+	case cc.Service != nil:
+		info.Service = cc.Service.Namespace + "/" + cc.Service.Name
+		if cc.Service.Port != nil {
+			info.Service += ":" + strconv.Itoa(int(*cc.Service.Port))
+		}
+	case cc.URL != nil:
+		info.Service = *cc.URL
+	}
+	return info
+}
+
+func putCacheAPIInventory(clusterName string, inventory APIInventory) {
+	apiInventoryLock.Lock()
+	defer apiInventoryLock.Unlock()
+
+	if len(localCacheAPIInventory) == 0 {
+		localCacheAPIInventory = map[string]APIInventory{}
+	}
+	localCacheAPIInventory[clusterName] = inventory
+}
+
+func deleteCacheAPIInventory(clusterName string) {
+	apiInventoryLock.Lock()
+	defer apiInventoryLock.Unlock()
+
+	delete(localCacheAPIInventory, clusterName)
+}
+
+func GetCacheAPIInventoryWithClusterName(clusterName string) (APIInventory, bool) {
+	apiInventoryLock.Lock()
+	defer apiInventoryLock.Unlock()
+
+	if len(localCacheAPIInventory) == 0 {
+		return APIInventory{}, false
+	}
+	inventory, ok := localCacheAPIInventory[clusterName]
+	return inventory, ok
+}
+
+func GetAllCacheAPIInventory() []APIInventory {
+	apiInventoryLock.Lock()
+	defer apiInventoryLock.Unlock()
+
+	list := make([]APIInventory, 0, len(localCacheAPIInventory))
+	for _, inventory := range localCacheAPIInventory {
+		list = append(list, inventory)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,62 @@
+package resource
+
+import (
+	"k8s.io/klog/v2"
+)
+
+// DeleteCacheWithClusterName remove all collect results of the cluster, such as decommissioned
+func DeleteCacheWithClusterName(clusterName string) {
+	deleteCacheClusterStatus(clusterName)
+	deleteCacheSummaryResource(clusterName)
+	deleteCacheNamespaceStatus(clusterName)
+	deleteCacheClusterHealth(clusterName)
+	deleteCacheAPIInventory(clusterName)
+	deleteCacheImageInventory(clusterName)
+	deleteCacheSecurityPosture(clusterName)
+	deleteCacheRBACExposure(clusterName)
+	deleteCacheCertificates(clusterName)
+}
+
+// PruneCache remove collect results of clusters not in clusterNames, a collect cycle running while
+// cluster removed may put results after DeleteCacheWithClusterName.
+func PruneCache(clusterNames []string) {
+	keep := map[string]bool{}
+	for _, clusterName := range clusterNames {
+		keep[clusterName] = true
+	}
+	cached := map[string]bool{}
+	for _, cs := range GetAllCacheClusterStatus() {
+		cached[cs.ClusterName] = true
+	}
+	for _, sru := range GetAllCacheSummaryResource() {
+		cached[sru.ClusterName] = true
+	}
+	for _, ns := range GetAllCacheNamespaceStatus() {
+		cached[ns.ClusterName] = true
+	}
+	for _, health := range GetAllCacheClusterHealth() {
+		cached[health.ClusterName] = true
+	}
+	for _, inventory := range GetAllCacheAPIInventory() {
+		cached[inventory.ClusterName] = true
+	}
+	for _, inventory := range GetAllCacheImageInventory() {
+		cached[inventory.ClusterName] = true
+	}
+	for _, posture := range GetAllCacheSecurityPosture() {
+		cached[posture.ClusterName] = true
+	}
+	for _, exposure := range GetAllCacheRBACExposure() {
+		cached[exposure.ClusterName] = true
+	}
+	for _, cc := range GetAllCacheCertificates() {
+		cached[cc.ClusterName] = true
+	}
+
+	for clusterName := range cached {
+		if !keep[clusterName] {
+			klog.Infof("cluster %s removed, prune collect cache", clusterName)
+			DeleteCacheWithClusterName(clusterName)
+		}
+	}
+}
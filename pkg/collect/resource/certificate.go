@@ -0,0 +1,390 @@
+package resource
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/kube"
+	clustetgatewayv1aplpha1 "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/symcn/api"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	CertSourceHubClient           = "hub-client"
+	CertSourceRegistrationSecret  = "registration-secret"
+	CertSourceGatewayServing      = "gateway-serving"
+	CertSourceGatewayClient       = "gateway-client"
+	CertSourceControlPlaneCA      = "control-plane-ca"
+	CertSourceTLSSecret           = "tls-secret"
+	CertStateOK                   = "OK"
+	CertStateWarning              = "Warning"
+	CertStateCritical             = "Critical"
+	CertStateExpired              = "Expired"
+	extensionAuthenticationConfig = "extension-apiserver-authentication"
+)
+
+var (
+	// CertWarnThreshold certificates expire within it are Warning
+	CertWarnThreshold = time.Hour * 24 * 30
+	// CertCriticalThreshold certificates expire within it are Critical
+	CertCriticalThreshold = time.Hour * 24 * 7
+	// CertSecretNamespaces namespaces TLS secrets are tracked
+	CertSecretNamespaces = []string{}
+	// HubKubeconfigSecret registration agent secret contains hub client cert, namespace/name
+	HubKubeconfigSecret = "open-cluster-management-agent/hub-kubeconfig-secret"
+
+	certDialTimeout = time.Second * 5
+
+	certificateExpirySeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "clustermanager",
+		Name:      "certificate_expiry_seconds",
+		Help:      "Seconds until certificate expires, negative means expired.",
+	}, []string{"cluster", "source", "namespace", "name"})
+
+	certLock                 sync.Mutex
+	localCacheCertificates   = map[string]ClusterCertificates{}
+	certificateMetricsLabels = map[string][]prometheus.Labels{}
+)
+
+func init() {
+	prometheus.MustRegister(certificateExpirySeconds)
+}
+
+type ClusterCertificates struct {
+	ClusterName string
+	// State the worst state of all certificates
+	State        string
+	Certificates []CertificateInfo
+}
+
+type CertificateInfo struct {
+	Source    string
+	Namespace string
+	Name      string
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	ExpiresIn string
+	State     string
+}
+
+// CollectCertificates track expiry of hub client cert from approved CSR and registration secret,
+// cluster-gateway serving and client certs, control-plane CAs and TLS secrets in CertSecretNamespaces.
+func CollectCertificates(cli api.MingleProxyClient) {
+	clusterName := cli.GetClusterCfgInfo().GetName()
+	certs := []CertificateInfo{}
+	certs = append(certs, getHubClientCertificates(clusterName)...)
+	certs = append(certs, getRegistrationSecretCertificates(cli)...)
+	certs = append(certs, getGatewayCertificates(clusterName)...)
+	certs = append(certs, getControlPlaneCACertificates(cli)...)
+	certs = append(certs, getTLSSecretCertificates(cli)...)
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+
+	cc := ClusterCertificates{
+		ClusterName:  clusterName,
+		State:        CertStateOK,
+		Certificates: certs,
+	}
+	for _, cert := range certs {
+		if certStateRank(cert.State) > certStateRank(cc.State) {
+			cc.State = cert.State
+		}
+	}
+	putCacheCertificates(clusterName, cc)
+	updateCertificateMetrics(cc)
+
+	data, _ := json.Marshal(cc)
+	klog.V(4).Infof("get certificates:\n%s", string(data))
+}
+
+// getHubClientCertificates latest approved CSR of the cluster on hub
+func getHubClientCertificates(clusterName string) []CertificateInfo {
+	if kube.ManagerPlaneClusterClient == nil {
+		return nil
+	}
+	csrs := &certificatesv1.CertificateSigningRequestList{}
+	err := kube.ManagerPlaneClusterClient.List(csrs, &client.ListOptions{
+		LabelSelector: labels.Set{kube.ClusterNameLabel: clusterName}.AsSelector(),
+	})
+	if err != nil {
+		klog.Warningf("failed to list csr of cluster %s: %v", clusterName, err)
+		return nil
+	}
+
+	var latest *certificatesv1.CertificateSigningRequest
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if len(csr.Status.Certificate) == 0 {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&csr.CreationTimestamp) {
+			latest = csr
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return buildCertificateInfos(CertSourceHubClient, "", latest.Name, latest.Status.Certificate)
+}
+
+func getRegistrationSecretCertificates(cli api.MingleProxyClient) []CertificateInfo {
+	namespace, name := splitNamespacedName(HubKubeconfigSecret)
+	secret, err := cli.GetKubeInterface().CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Warningf("failed to get secret %s: %v", HubKubeconfigSecret, err)
+		}
+		return nil
+	}
+	return buildCertificateInfos(CertSourceRegistrationSecret, namespace, name, secret.Data[corev1.TLSCertKey])
+}
+
+// getGatewayCertificates serving cert of Const endpoint and x509 client credential of ClusterGateway
+func getGatewayCertificates(clusterName string) []CertificateInfo {
+	if kube.ManagerPlaneClusterClient == nil {
+		return nil
+	}
+	gvr := (&clustetgatewayv1aplpha1.ClusterGateway{}).GetGroupVersionResource()
+	obj, err := kube.ManagerPlaneClusterClient.GetDynamicInterface().Resource(gvr).Get(context.TODO(), clusterName, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("failed to get cluster gateway %s: %v", clusterName, err)
+		return nil
+	}
+	gateway := &clustetgatewayv1aplpha1.ClusterGateway{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), gateway); err != nil {
+		klog.Warningf("failed to convert cluster gateway %s: %v", clusterName, err)
+		return nil
+	}
+
+	certs := []CertificateInfo{}
+	access := gateway.Spec.Access
+	if access.Endpoint != nil && access.Endpoint.Const != nil {
+		if serving, err := getServingCertificates(access.Endpoint.Const.Address); err != nil {
+			klog.Warningf("failed to get serving certificate of cluster %s: %v", clusterName, err)
+		} else if len(serving) > 0 {
+			certs = append(certs, newCertificateInfo(CertSourceGatewayServing, "", access.Endpoint.Const.Address, serving[0]))
+		}
+	}
+	if access.Credential != nil && access.Credential.X509 != nil {
+		certs = append(certs, buildCertificateInfos(CertSourceGatewayClient, "", clusterName, access.Credential.X509.Certificate)...)
+	}
+	return certs
+}
+
+// getServingCertificates tls handshake only reads peer certificates, verification is not needed
+func getServingCertificates(address string) ([]*x509.Certificate, error) {
+	host := address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: certDialTimeout}, "tcp", host, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+// getControlPlaneCACertificates client and front-proxy CA published by apiserver, such as kubeadm ca.crt and front-proxy-ca.crt
+func getControlPlaneCACertificates(cli api.MingleProxyClient) []CertificateInfo {
+	cm, err := cli.GetKubeInterface().CoreV1().ConfigMaps(ControlPlaneNamespace).Get(context.TODO(), extensionAuthenticationConfig, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Warningf("failed to get configmap %s: %v", extensionAuthenticationConfig, err)
+		}
+		return nil
+	}
+	certs := []CertificateInfo{}
+	for _, key := range []string{"client-ca-file", "requestheader-client-ca-file"} {
+		certs = append(certs, buildCertificateInfos(CertSourceControlPlaneCA, ControlPlaneNamespace, key, []byte(cm.Data[key]))...)
+	}
+	return certs
+}
+
+func getTLSSecretCertificates(cli api.MingleProxyClient) []CertificateInfo {
+	certs := []CertificateInfo{}
+	for _, namespace := range CertSecretNamespaces {
+		secrets, err := cli.GetKubeInterface().CoreV1().Secrets(namespace).List(context.TODO(), metav1.ListOptions{
+			FieldSelector: "type=" + string(corev1.SecretTypeTLS),
+		})
+		if err != nil {
+			klog.Warningf("failed to list tls secrets in %s: %v", namespace, err)
+			continue
+		}
+		for _, secret := range secrets.Items {
+			// only the leaf certificate of chain is tracked
+			if infos := buildCertificateInfos(CertSourceTLSSecret, namespace, secret.Name, secret.Data[corev1.TLSCertKey]); len(infos) > 0 {
+				certs = append(certs, infos[0])
+			}
+		}
+	}
+	return certs
+}
+
+func buildCertificateInfos(source, namespace, name string, data []byte) []CertificateInfo {
+	infos := []CertificateInfo{}
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			klog.Warningf("failed to parse certificate %s/%s: %v", namespace, name, err)
+			continue
+		}
+		infos = append(infos, newCertificateInfo(source, namespace, name, cert))
+	}
+	return infos
+}
+
+func newCertificateInfo(source, namespace, name string, cert *x509.Certificate) CertificateInfo {
+	expiresIn := time.Until(cert.NotAfter)
+	return CertificateInfo{
+		Source:    source,
+		Namespace: namespace,
+		Name:      name,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		ExpiresIn: expiresIn.Truncate(time.Minute).String(),
+		State:     getCertState(expiresIn),
+	}
+}
+
+func getCertState(expiresIn time.Duration) string {
+	switch {
+	case expiresIn <= 0:
+		return CertStateExpired
+	case expiresIn <= CertCriticalThreshold:
+		return CertStateCritical
+	case expiresIn <= CertWarnThreshold:
+		return CertStateWarning
+	}
+	return CertStateOK
+}
+
+func certStateRank(state string) int {
+	switch state {
+	case CertStateWarning:
+		return 1
+	case CertStateCritical:
+		return 2
+	case CertStateExpired:
+		return 3
+	}
+	return 0
+}
+
+func splitNamespacedName(s string) (string, string) {
+	if i := strings.Index(s, "/"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+// updateCertificateMetrics replace series of the cluster, rotated certificates should not keep stale series
+func updateCertificateMetrics(cc ClusterCertificates) {
+	certLock.Lock()
+	defer certLock.Unlock()
+
+	for _, l := range certificateMetricsLabels[cc.ClusterName] {
+		certificateExpirySeconds.Delete(l)
+	}
+	list := make([]prometheus.Labels, 0, len(cc.Certificates))
+	for _, cert := range cc.Certificates {
+		l := prometheus.Labels{"cluster": cc.ClusterName, "source": cert.Source, "namespace": cert.Namespace, "name": cert.Name}
+		// multiple certs with the same labels such as CA bundle, the earliest expiry is kept
+		if g, err := certificateExpirySeconds.GetMetricWith(l); err == nil && !containsLabels(list, l) {
+			g.Set(time.Until(cert.NotAfter).Seconds())
+			list = append(list, l)
+		}
+	}
+	certificateMetricsLabels[cc.ClusterName] = list
+}
+
+func containsLabels(list []prometheus.Labels, l prometheus.Labels) bool {
+	for _, item := range list {
+		if item["source"] == l["source"] && item["namespace"] == l["namespace"] && item["name"] == l["name"] {
+			return true
+		}
+	}
+	return false
+}
+
+func putCacheCertificates(clusterName string, cc ClusterCertificates) {
+	certLock.Lock()
+	defer certLock.Unlock()
+
+	if len(localCacheCertificates) == 0 {
+		localCacheCertificates = map[string]ClusterCertificates{}
+	}
+	localCacheCertificates[clusterName] = cc
+}
+
+func deleteCacheCertificates(clusterName string) {
+	certLock.Lock()
+	defer certLock.Unlock()
+
+	for _, l := range certificateMetricsLabels[clusterName] {
+		certificateExpirySeconds.Delete(l)
+	}
+	delete(certificateMetricsLabels, clusterName)
+	delete(localCacheCertificates, clusterName)
+}
+
+func GetCacheCertificatesWithClusterName(clusterName string) (ClusterCertificates, bool) {
+	certLock.Lock()
+	defer certLock.Unlock()
+
+	if len(localCacheCertificates) == 0 {
+		return ClusterCertificates{}, false
+	}
+	cc, ok := localCacheCertificates[clusterName]
+	return cc, ok
+}
+
+func GetAllCacheCertificates() []ClusterCertificates {
+	certLock.Lock()
+	defer certLock.Unlock()
+
+	list := make([]ClusterCertificates, 0, len(localCacheCertificates))
+	for _, cc := range localCacheCertificates {
+		list = append(list, cc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
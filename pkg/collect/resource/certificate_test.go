@@ -0,0 +1,26 @@
+package resource
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetCertState(t *testing.T) {
+	tests := []struct {
+		expiresIn time.Duration
+		state     string
+	}{
+		{-time.Hour, CertStateExpired},
+		{0, CertStateExpired},
+		{time.Hour, CertStateCritical},
+		{CertCriticalThreshold, CertStateCritical},
+		{CertCriticalThreshold + time.Minute, CertStateWarning},
+		{CertWarnThreshold, CertStateWarning},
+		{CertWarnThreshold + time.Minute, CertStateOK},
+	}
+	for _, tt := range tests {
+		if state := getCertState(tt.expiresIn); state != tt.state {
+			t.Errorf("expires in %s expected %s, got %s", tt.expiresIn, tt.state, state)
+		}
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"strings"
+	"sort"
 	"sync"
 
 	"github.com/symcn/api"
@@ -26,14 +27,22 @@ type ClusterStatus struct {
 	ClusterName       string
 	KubernetesVersion string
 	Platform          string
+	Distribution      DistributionInfo
 	Healthz           bool
 	Livez             bool
 	Readyz            bool
 	ClusterCIDR       string
 	ServiceCIDR       string
+	AllocatedPodIPs   int32
 	NodeStatistics    NodeStatistics
 	Allocatable       corev1.ResourceList
 	Capacity          corev1.ResourceList
+	Requested         corev1.ResourceList
+	Utilization       ClusterUtilization
+	Nodes             []NodeInfo
+	ControlPlane      ControlPlaneStatus
+	// DeprecatedAPIUsages objects use api versions in RemovedAPIs
+	DeprecatedAPIUsages []DeprecatedAPIUsage
 }
 
 type NodeStatistics struct {
@@ -43,7 +52,8 @@ type NodeStatistics struct {
 	LostNodes     int32
 }
 
-func CollectClusterStatus(cli api.MingleProxyClient) {
+// CollectClusterStatus pods is listed once per collect cycle, nil when list failed.
+func CollectClusterStatus(cli api.MingleProxyClient, pods *corev1.PodList) {
 	clusterVersion, err := cli.GetKubeInterface().Discovery().ServerVersion()
 	if err != nil {
 		klog.Warningf("failed to collect kubernetes version: %v", err)
@@ -68,19 +78,35 @@ func CollectClusterStatus(cli api.MingleProxyClient) {
 		klog.Warningf("failed to discover service CIDR: %v", err)
 	}
 
+	if pods == nil {
+		// keep previous cache, empty pods would report zero requests and pod ips
+		klog.Warningf("skip cluster status of %s, pods not listed", cli.GetClusterCfgInfo().GetName())
+		return
+	}
+	utilization := buildClusterUtilization(nodes, pods)
+
 	clusterStatus := ClusterStatus{
-		ClusterName:       cli.GetClusterCfgInfo().GetName(),
-		KubernetesVersion: clusterVersion.GitVersion,
-		Platform:          clusterVersion.Platform,
-		Healthz:           getHealthStatus(cli, "/healthz"),
-		Livez:             getHealthStatus(cli, "/livez"),
-		Readyz:            getHealthStatus(cli, "/readyz"),
-		ClusterCIDR:       clusterCIDR,
-		ServiceCIDR:       serviceCIDR,
-		NodeStatistics:    nodeStatistics,
-		Allocatable:       allocatable,
-		Capacity:          capacity,
+		ClusterName:         cli.GetClusterCfgInfo().GetName(),
+		KubernetesVersion:   clusterVersion.GitVersion,
+		Platform:            clusterVersion.Platform,
+		Distribution:        detectDistribution(cli, nodes, clusterVersion),
+		Healthz:             getHealthStatus(cli, "/healthz"),
+		Livez:               getHealthStatus(cli, "/livez"),
+		Readyz:              getHealthStatus(cli, "/readyz"),
+		ClusterCIDR:         clusterCIDR,
+		ServiceCIDR:         serviceCIDR,
+		AllocatedPodIPs:     countAllocatedPodIPs(pods),
+		NodeStatistics:      nodeStatistics,
+		Allocatable:         allocatable,
+		Capacity:            capacity,
+		Requested:           utilization.Requests,
+		Utilization:         utilization,
+		Nodes:               getNodeInventory(nodes, getNodeUsage(cli)),
+		ControlPlane:        getControlPlaneStatus(cli),
+		DeprecatedAPIUsages: getDeprecatedAPIUsages(cli),
 	}
+	putCacheClusterStatus(clusterStatus.ClusterName, clusterStatus)
+
 	data, _ := json.MarshalIndent(clusterStatus, "", "  ")
 	klog.Infof("get cluster status:\n%s", string(data))
 }
@@ -117,34 +143,47 @@ func getNodeCondition(status *corev1.NodeStatus, conditiontype corev1.NodeCondit
 	return -1, nil
 }
 
+// getNodeResource sum every resource name found in node capacity and allocatable,
+// include extended resources such as nvidia.com/gpu, hugepages and ephemeral-storage.
 func getNodeResource(nodes *corev1.NodeList) (Capacity, Allocatable corev1.ResourceList) {
-	var capacityCPU, capacityMem, allocatableCPU, allocatableMem resource.Quantity
+	Capacity = corev1.ResourceList{}
+	Allocatable = corev1.ResourceList{}
 	for _, node := range nodes.Items {
-		capacityCPU.Add(*node.Status.Capacity.Cpu())
-		capacityMem.Add(*node.Status.Capacity.Memory())
-		allocatableCPU.Add(*node.Status.Allocatable.Cpu())
-		allocatableMem.Add(*node.Status.Allocatable.Memory())
+		AddResourceList(Capacity, node.Status.Capacity)
+		AddResourceList(Allocatable, node.Status.Allocatable)
 	}
+	return
+}
 
-	Capacity = make(map[corev1.ResourceName]resource.Quantity)
-	Allocatable = make(map[corev1.ResourceName]resource.Quantity)
+func isPodScheduledAndActive(pod *corev1.Pod) bool {
+	if pod.Spec.NodeName == "" {
+		return false
+	}
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed
+}
 
-	Capacity[corev1.ResourceCPU] = capacityCPU
-	Capacity[corev1.ResourceMemory] = capacityMem
-	Allocatable[corev1.ResourceCPU] = allocatableCPU
-	Allocatable[corev1.ResourceMemory] = allocatableMem
-	return
+// ListPods list all pods of cluster, shared by collectors in one collect cycle
+func ListPods(cli api.MingleProxyClient) (*corev1.PodList, error) {
+	pods := &corev1.PodList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), pods); err != nil {
+		return nil, fmt.Errorf("list pods of cluster %s failed: %+v", cli.GetClusterCfgInfo().GetName(), err)
+	}
+	return pods, nil
 }
 
-func discoverClusterCIDR(cli api.MingleProxyClient) (string, error) {
-	clusterIPRange := findPodCommandParameter(cli, "kube-apiserver", "--service-cluster-ip-range")
-	if clusterIPRange != "" {
-		return clusterIPRange, nil
+// AddResourceList add all quantity in src to dst
+func AddResourceList(dst, src corev1.ResourceList) {
+	for name, value := range src {
+		q, ok := dst[name]
+		if !ok {
+			q = resource.Quantity{}
+		}
+		q.Add(value)
+		dst[name] = q
 	}
-	return "", errors.New("can't get ClusterIPRange")
 }
 
-func discoverServiceCIDR(cli api.MingleProxyClient) (string, error) {
+func discoverClusterCIDR(cli api.MingleProxyClient) (string, error) {
 	podIPRange := findPodIPRangerKubeController(cli)
 	if podIPRange != "" {
 		return podIPRange, nil
@@ -163,6 +202,14 @@ func discoverServiceCIDR(cli api.MingleProxyClient) (string, error) {
 	return "", errors.New("can't get PodIPRange")
 }
 
+func discoverServiceCIDR(cli api.MingleProxyClient) (string, error) {
+	serviceIPRange := findPodCommandParameter(cli, "kube-apiserver", "--service-cluster-ip-range")
+	if serviceIPRange != "" {
+		return serviceIPRange, nil
+	}
+	return "", errors.New("can't get ServiceIPRange")
+}
+
 func findPodIPRangerKubeController(cli api.MingleProxyClient) string {
 	return findPodCommandParameter(cli, "kube-controller-manager", "--cluster-cidr")
 }
@@ -192,11 +239,15 @@ func findPodCommandParameter(cli api.MingleProxyClient, labelSelectorValue, para
 	if err != nil || pod == nil {
 		return ""
 	}
-	for _, container := range pod.Spec.Containers {
-		if val := getParaValue(container.Command, parameter); val != "" {
-			return val
-		}
-		if val := getParaValue(container.Args, parameter); val != "" {
+	return getContainerFlag(pod.Spec.Containers, parameter)
+}
+
+// getContainerFlag returns value of parameter from the first container set it, parse by parseFlags.
+func getContainerFlag(containers []corev1.Container, parameter string) string {
+	for _, container := range containers {
+		flags := map[string]string{}
+		parseFlags(flags, append(append([]string{}, container.Command...), container.Args...))
+		if val := flags[parameter]; val != "" && val != "true" {
 			return val
 		}
 	}
@@ -224,21 +275,20 @@ func findPod(cli api.MingleProxyClient, labelSelectorKey, labelSelectorValue str
 	return &pods.Items[0], nil
 }
 
-func getParaValue(lists []string, parameter string) string {
-	for _, arg := range lists {
-		if strings.HasPrefix(arg, parameter) {
-			return strings.Split(arg, "=")[1]
+// countAllocatedPodIPs returns the number of pods holding an ip from the pod CIDR,
+// hostNetwork pods and terminated pods are not counted.
+func countAllocatedPodIPs(pods *corev1.PodList) int32 {
+	var count int32
+	for _, pod := range pods.Items {
+		if pod.Spec.HostNetwork || pod.Status.PodIP == "" {
+			continue
 		}
-		// Handing the case where the command is in the form of /bin/sh -c exec ....
-		if strings.Contains(arg, " ") {
-			for _, subArg := range strings.Split(arg, " ") {
-				if strings.HasPrefix(subArg, parameter) {
-					return strings.Split(subArg, "=")[1]
-				}
-			}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
 		}
+		count++
 	}
-	return ""
+	return count
 }
 
 func getHealthStatus(cli api.MingleProxyClient, path string) bool {
@@ -257,7 +307,14 @@ func putCacheClusterStatus(clusterName string, clusterStatus ClusterStatus) {
 	localCacheClusterStatus[clusterName] = clusterStatus
 }
 
-func getCacheClusterStatusWithClusterName(clusterName string) (ClusterStatus, bool) {
+func deleteCacheClusterStatus(clusterName string) {
+	clusterLock.Lock()
+	defer clusterLock.Unlock()
+
+	delete(localCacheClusterStatus, clusterName)
+}
+
+func GetCacheClusterStatusWithClusterName(clusterName string) (ClusterStatus, bool) {
 	clusterLock.Lock()
 	defer clusterLock.Unlock()
 
@@ -267,3 +324,17 @@ func getCacheClusterStatusWithClusterName(clusterName string) (ClusterStatus, bo
 	clusterStatus, ok := localCacheClusterStatus[clusterName]
 	return clusterStatus, ok
 }
+
+func GetAllCacheClusterStatus() []ClusterStatus {
+	clusterLock.Lock()
+	defer clusterLock.Unlock()
+
+	list := make([]ClusterStatus, 0, len(localCacheClusterStatus))
+	for _, clusterStatus := range localCacheClusterStatus {
+		list = append(list, clusterStatus)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,95 @@
+package resource
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const resourceGPU corev1.ResourceName = "nvidia.com/gpu"
+
+func resourceList(pairs ...string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		list[corev1.ResourceName(pairs[i])] = resource.MustParse(pairs[i+1])
+	}
+	return list
+}
+
+func expectQuantities(t *testing.T, name string, got corev1.ResourceList, expected map[corev1.ResourceName]string) {
+	t.Helper()
+	for resourceName, value := range expected {
+		q, ok := got[resourceName]
+		if !ok {
+			t.Errorf("%s: %s not found in %v", name, resourceName, got)
+			continue
+		}
+		if q.Cmp(resource.MustParse(value)) != 0 {
+			t.Errorf("%s: %s expected %s, got %s", name, resourceName, value, q.String())
+		}
+	}
+}
+
+func syntheticNodes() *corev1.NodeList {
+	return &corev1.NodeList{Items: []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cpu-node"},
+			Status: corev1.NodeStatus{
+				Capacity:    resourceList("cpu", "8", "memory", "32Gi", "pods", "110", "ephemeral-storage", "100Gi", "hugepages-2Mi", "1Gi"),
+				Allocatable: resourceList("cpu", "7500m", "memory", "30Gi", "pods", "110", "ephemeral-storage", "90Gi", "hugepages-2Mi", "1Gi"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu-node"},
+			Status: corev1.NodeStatus{
+				Capacity:    resourceList("cpu", "16", "memory", "64Gi", "pods", "110", "ephemeral-storage", "200Gi", string(resourceGPU), "4"),
+				Allocatable: resourceList("cpu", "15500m", "memory", "62Gi", "pods", "110", "ephemeral-storage", "180Gi", string(resourceGPU), "4"),
+			},
+		},
+	}}
+}
+
+func TestGetNodeResource(t *testing.T) {
+	capacity, allocatable := getNodeResource(syntheticNodes())
+	expectQuantities(t, "capacity", capacity, map[corev1.ResourceName]string{
+		corev1.ResourceCPU:              "24",
+		corev1.ResourceMemory:           "96Gi",
+		corev1.ResourcePods:             "220",
+		corev1.ResourceEphemeralStorage: "300Gi",
+		"hugepages-2Mi":                 "1Gi",
+		resourceGPU:                     "4",
+	})
+	expectQuantities(t, "allocatable", allocatable, map[corev1.ResourceName]string{
+		corev1.ResourceCPU:              "23",
+		corev1.ResourceMemory:           "92Gi",
+		corev1.ResourceEphemeralStorage: "270Gi",
+		resourceGPU:                     "4",
+	})
+
+	capacity, allocatable = getNodeResource(&corev1.NodeList{})
+	if len(capacity) != 0 || len(allocatable) != 0 {
+		t.Errorf("expected empty resource for no nodes, got %v %v", capacity, allocatable)
+	}
+}
+
+func TestGetContainerFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []corev1.Container
+		expected   string
+	}{
+		{"equal form", []corev1.Container{{Command: []string{"kube-controller-manager", "--cluster-cidr=10.244.0.0/16"}}}, "10.244.0.0/16"},
+		{"space form", []corev1.Container{{Command: []string{"kube-controller-manager"}, Args: []string{"--cluster-cidr", "10.244.0.0/16"}}}, "10.244.0.0/16"},
+		{"shell form", []corev1.Container{{Command: []string{"/bin/sh", "-c", "exec kube-apiserver --cluster-cidr 10.96.0.0/12 --v=2"}}}, "10.96.0.0/12"},
+		{"prefix only", []corev1.Container{{Command: []string{"kube-controller-manager", "--cluster-cidr-extra=10.1.0.0/16"}}}, ""},
+		{"bare flag", []corev1.Container{{Command: []string{"kube-controller-manager", "--cluster-cidr"}}}, ""},
+		{"second container", []corev1.Container{{Name: "sidecar"}, {Args: []string{"--cluster-cidr=10.244.0.0/16"}}}, "10.244.0.0/16"},
+	}
+	for _, tt := range tests {
+		if val := getContainerFlag(tt.containers, "--cluster-cidr"); val != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, val)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package resource
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/symcn/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// ControlPlaneComponents component label value of control-plane pods
+	ControlPlaneComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd"}
+	ControlPlaneNamespace  = "kube-system"
+
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+type ControlPlaneStatus struct {
+	// Visible is false when control-plane pods can't be found, such as managed offerings
+	Visible    bool
+	Components []ComponentStatus
+}
+
+type ComponentStatus struct {
+	Name             string
+	Version          string
+	Replicas         int32
+	ReadyReplicas    int32
+	StaticPod        bool
+	Healthy          bool
+	Flags            map[string]string
+	FeatureGates     map[string]string
+	AdmissionPlugins []string
+}
+
+func getControlPlaneStatus(cli api.MingleProxyClient) ControlPlaneStatus {
+	status := ControlPlaneStatus{Components: []ComponentStatus{}}
+	for _, component := range ControlPlaneComponents {
+		pods := &corev1.PodList{}
+		err := cli.GetRuntimeClient().List(context.TODO(), pods,
+			client.InNamespace(ControlPlaneNamespace),
+			client.MatchingLabels{"component": component},
+		)
+		if err != nil {
+			klog.Warningf("failed to list %s pods: %v", component, err)
+			continue
+		}
+		if len(pods.Items) == 0 {
+			continue
+		}
+		status.Components = append(status.Components, buildComponentStatus(component, pods.Items))
+	}
+	status.Visible = len(status.Components) > 0
+	return status
+}
+
+func buildComponentStatus(name string, pods []corev1.Pod) ComponentStatus {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	cs := ComponentStatus{
+		Name:             name,
+		Replicas:         int32(len(pods)),
+		Flags:            map[string]string{},
+		FeatureGates:     map[string]string{},
+		AdmissionPlugins: []string{},
+	}
+	for i := range pods {
+		if isPodReady(&pods[i]) {
+			cs.ReadyReplicas++
+		}
+	}
+	cs.Healthy = cs.ReadyReplicas == cs.Replicas
+
+	// flags and version are read from the first pod, all replicas should be the same
+	pod := &pods[0]
+	_, cs.StaticPod = pod.Annotations[mirrorPodAnnotation]
+	if len(pod.Spec.Containers) > 0 {
+		container := pod.Spec.Containers[0]
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				container = c
+				break
+			}
+		}
+		_, cs.Version, _ = ParseImageReference(container.Image)
+		// args follow command, a flag at the end of command may take its value from args
+		parseFlags(cs.Flags, append(append([]string{}, container.Command...), container.Args...))
+	}
+
+	for _, gate := range splitFlagValue(cs.Flags["--feature-gates"]) {
+		kv := strings.SplitN(gate, "=", 2)
+		if len(kv) == 2 {
+			cs.FeatureGates[kv[0]] = kv[1]
+		}
+	}
+	cs.AdmissionPlugins = splitFlagValue(cs.Flags["--enable-admission-plugins"])
+	return cs
+}
+
+// parseFlags parse --key=value, --key value and --key into flags, include /bin/sh -c exec ... form.
+// Token after --key is its value unless it's another flag.
+func parseFlags(flags map[string]string, list []string) {
+	items := []string{}
+	for _, arg := range list {
+		items = append(items, strings.Fields(arg)...)
+	}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		if !strings.HasPrefix(item, "--") {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		switch {
+		case len(kv) == 2:
+			flags[kv[0]] = kv[1]
+		case i+1 < len(items) && !strings.HasPrefix(items[i+1], "-"):
+			flags[kv[0]] = items[i+1]
+			i++
+		default:
+			flags[kv[0]] = "true"
+		}
+	}
+}
+
+func splitFlagValue(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
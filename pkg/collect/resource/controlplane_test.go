@@ -0,0 +1,42 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     []string
+		expected map[string]string
+	}{
+		{
+			name:     "equal form",
+			list:     []string{"kube-apiserver", "--secure-port=6443", "--enable-admission-plugins=NodeRestriction,PodSecurity"},
+			expected: map[string]string{"--secure-port": "6443", "--enable-admission-plugins": "NodeRestriction,PodSecurity"},
+		},
+		{
+			name:     "space form",
+			list:     []string{"--feature-gates", "EphemeralContainers=true", "--secure-port", "6443"},
+			expected: map[string]string{"--feature-gates": "EphemeralContainers=true", "--secure-port": "6443"},
+		},
+		{
+			name:     "bool flags",
+			list:     []string{"--profiling", "--leader-elect", "-v=2", "--allow-privileged"},
+			expected: map[string]string{"--profiling": "true", "--leader-elect": "true", "--allow-privileged": "true"},
+		},
+		{
+			name:     "shell form",
+			list:     []string{"/bin/sh", "-c", "exec kube-scheduler --bind-address 0.0.0.0 --v=2 --leader-elect"},
+			expected: map[string]string{"--bind-address": "0.0.0.0", "--v": "2", "--leader-elect": "true"},
+		},
+	}
+	for _, tt := range tests {
+		flags := map[string]string{}
+		parseFlags(flags, tt.list)
+		if !reflect.DeepEqual(flags, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, flags)
+		}
+	}
+}
@@ -4,17 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/symcn/api"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
 )
 
 var (
-	ShowLabelKey = "app"
+	// ShowLabelKeys label keys identify the application of workload, first non-empty value is used
+	ShowLabelKeys = []string{"app", "app.kubernetes.io/name"}
+	// ShowLabelKey Deprecated: use ShowLabelKeys, non-empty value replaces the first entry of ShowLabelKeys
+	ShowLabelKey = ""
 
 	deployLock                sync.Mutex
 	localCacheSummaryResource = map[string]SummaryResourceUseage{}
@@ -25,6 +28,8 @@ type SummaryResourceUseage struct {
 	DeploymentStatistics  DeploymentStatistics
 	StatefulsetStatistics StatefulsetStatistics
 	DaemonsetStatistics   DaemonsetStatistics
+	// Workloads other kinds collected by workloadKinds, key is kind
+	Workloads map[string]WorkloadStatistics
 }
 
 type DeploymentStatistics struct {
@@ -44,33 +49,50 @@ type DeploymentStatus struct {
 	ShowName            string
 	Replicas            int32
 	ReadyReplicas       int32
+	AvailableReplicas   int32
 	UnavailableReplicas int32
 	Resource            Resouces
+	Rollout             RolloutStatus
 }
 
 type StatefulsetStatus struct {
-	Name          string
-	ShowName      string
-	Replicas      int32
-	ReadyReplicas int32
-	Resource      Resouces
-}
-
-type DaemonSetStatus struct {
 	Name              string
 	ShowName          string
-	NumberAvailable   int32
-	NumberUnavailable int32
-	CollisionCount    *int32
+	Replicas          int32
+	ReadyReplicas     int32
+	AvailableReplicas int32
+	CurrentReplicas   int32
 	Resource          Resouces
+	Rollout           RolloutStatus
+}
+
+type DaemonSetStatus struct {
+	Name                   string
+	ShowName               string
+	DesiredNumberScheduled int32
+	CurrentNumberScheduled int32
+	NumberMisscheduled     int32
+	NumberReady            int32
+	NumberAvailable        int32
+	NumberUnavailable      int32
+	CollisionCount         *int32
+	Resource               Resouces
+	Rollout                RolloutStatus
 }
 
+// Resouces Requests and Limits are summed from pod template,
+// Usage is summed from live metrics of all pods, empty when metrics api is not available.
 type Resouces struct {
-	Requests corev1.ResourceList
-	Limits   corev1.ResourceList
+	Requests   corev1.ResourceList
+	Limits     corev1.ResourceList
+	Usage      corev1.ResourceList
+	Efficiency map[corev1.ResourceName]float64
 }
 
-func CollectDeploymentStatus(cli api.MingleProxyClient) {
+func CollectDeploymentStatus(cli api.MingleProxyClient, pods *corev1.PodList) {
+	podUsage := buildPodUsageIndex(cli, pods)
+	revisions := buildRevisionIndex(cli, pods)
+
 	// deployment
 	deploymentStatistics := DeploymentStatistics{List: map[string][]DeploymentStatus{}}
 	deploys, err := getAllDeployment(cli)
@@ -81,7 +103,9 @@ func CollectDeploymentStatus(cli api.MingleProxyClient) {
 			if _, ok := deploymentStatistics.List[deploy.Namespace]; !ok {
 				deploymentStatistics.List[deploy.Namespace] = []DeploymentStatus{}
 			}
-			deploymentStatistics.List[deploy.Namespace] = append(deploymentStatistics.List[deploy.Namespace], buildDeploymentStatus(&deploy))
+			ds := buildDeploymentStatus(&deploy, revisions)
+			podUsage.attach(&ds.Resource, deploy.Namespace, deploy.Spec.Selector)
+			deploymentStatistics.List[deploy.Namespace] = append(deploymentStatistics.List[deploy.Namespace], ds)
 		}
 	}
 
@@ -95,7 +119,9 @@ func CollectDeploymentStatus(cli api.MingleProxyClient) {
 			if _, ok := statefulsetStatistics.List[statefulset.Namespace]; !ok {
 				statefulsetStatistics.List[statefulset.Namespace] = []StatefulsetStatus{}
 			}
-			statefulsetStatistics.List[statefulset.Namespace] = append(statefulsetStatistics.List[statefulset.Namespace], buildStatefulsetStatus(&statefulset))
+			ss := buildStatefulsetStatus(&statefulset)
+			podUsage.attach(&ss.Resource, statefulset.Namespace, statefulset.Spec.Selector)
+			statefulsetStatistics.List[statefulset.Namespace] = append(statefulsetStatistics.List[statefulset.Namespace], ss)
 		}
 	}
 
@@ -109,7 +135,9 @@ func CollectDeploymentStatus(cli api.MingleProxyClient) {
 			if _, ok := daemonsetStatistics.List[daemonset.Namespace]; !ok {
 				daemonsetStatistics.List[daemonset.Namespace] = []DaemonSetStatus{}
 			}
-			daemonsetStatistics.List[daemonset.Namespace] = append(daemonsetStatistics.List[daemonset.Namespace], buildDaemonsetStatus(&daemonset))
+			ds := buildDaemonsetStatus(&daemonset, revisions)
+			podUsage.attach(&ds.Resource, daemonset.Namespace, daemonset.Spec.Selector)
+			daemonsetStatistics.List[daemonset.Namespace] = append(daemonsetStatistics.List[daemonset.Namespace], ds)
 		}
 	}
 
@@ -118,7 +146,11 @@ func CollectDeploymentStatus(cli api.MingleProxyClient) {
 		DeploymentStatistics:  deploymentStatistics,
 		StatefulsetStatistics: statefulsetStatistics,
 		DaemonsetStatistics:   daemonsetStatistics,
+		Workloads:             collectWorkloadKinds(cli, pods),
 	}
+	linkHPATarget(&summary)
+
+	putCacheSummaryResource(summary.ClusterName, summary)
 
 	// data, _ := json.MarshalIndent(summary, "", "  ")
 	data, _ := json.Marshal(summary)
@@ -133,14 +165,16 @@ func getAllDeployment(cli api.MingleProxyClient) (*appsv1.DeploymentList, error)
 	}
 	return deploys, nil
 }
-func buildDeploymentStatus(deploy *appsv1.Deployment) DeploymentStatus {
+func buildDeploymentStatus(deploy *appsv1.Deployment, revisions *revisionIndex) DeploymentStatus {
 	ds := DeploymentStatus{
 		Name:                deploy.Name,
-		ShowName:            deploy.Labels[ShowLabelKey],
+		ShowName:            getShowName(deploy.Labels),
 		Replicas:            deploy.Status.Replicas,
 		ReadyReplicas:       deploy.Status.ReadyReplicas,
+		AvailableReplicas:   deploy.Status.AvailableReplicas,
 		UnavailableReplicas: deploy.Status.UnavailableReplicas,
 		Resource:            buildResource(deploy.Spec.Template.Spec.Containers),
+		Rollout:             buildDeploymentRollout(deploy, revisions),
 	}
 	return ds
 }
@@ -156,11 +190,14 @@ func getAllStatefulset(cli api.MingleProxyClient) (*appsv1.StatefulSetList, erro
 
 func buildStatefulsetStatus(statefulset *appsv1.StatefulSet) StatefulsetStatus {
 	ss := StatefulsetStatus{
-		Name:          statefulset.Name,
-		ShowName:      statefulset.Labels[ShowLabelKey],
-		Replicas:      statefulset.Status.Replicas,
-		ReadyReplicas: statefulset.Status.AvailableReplicas,
-		Resource:      buildResource(statefulset.Spec.Template.Spec.Containers),
+		Name:              statefulset.Name,
+		ShowName:          getShowName(statefulset.Labels),
+		Replicas:          statefulset.Status.Replicas,
+		ReadyReplicas:     statefulset.Status.ReadyReplicas,
+		AvailableReplicas: statefulset.Status.AvailableReplicas,
+		CurrentReplicas:   statefulset.Status.CurrentReplicas,
+		Resource:          buildResource(statefulset.Spec.Template.Spec.Containers),
+		Rollout:           buildStatefulsetRollout(statefulset),
 	}
 	return ss
 }
@@ -174,40 +211,44 @@ func getAllDeamonset(cli api.MingleProxyClient) (*appsv1.DaemonSetList, error) {
 	return daemonsets, nil
 }
 
-func buildDaemonsetStatus(daemonset *appsv1.DaemonSet) DaemonSetStatus {
+func buildDaemonsetStatus(daemonset *appsv1.DaemonSet, revisions *revisionIndex) DaemonSetStatus {
 	ds := DaemonSetStatus{
-		Name:              daemonset.Name,
-		ShowName:          daemonset.Labels[ShowLabelKey],
-		NumberAvailable:   daemonset.Status.NumberAvailable,
-		NumberUnavailable: daemonset.Status.NumberUnavailable,
-		CollisionCount:    daemonset.Status.CollisionCount,
-		Resource:          buildResource(daemonset.Spec.Template.Spec.Containers),
+		Name:                   daemonset.Name,
+		ShowName:               getShowName(daemonset.Labels),
+		DesiredNumberScheduled: daemonset.Status.DesiredNumberScheduled,
+		CurrentNumberScheduled: daemonset.Status.CurrentNumberScheduled,
+		NumberMisscheduled:     daemonset.Status.NumberMisscheduled,
+		NumberReady:            daemonset.Status.NumberReady,
+		NumberAvailable:        daemonset.Status.NumberAvailable,
+		NumberUnavailable:      daemonset.Status.NumberUnavailable,
+		CollisionCount:         daemonset.Status.CollisionCount,
+		Resource:               buildResource(daemonset.Spec.Template.Spec.Containers),
+		Rollout:                buildDaemonsetRollout(daemonset, revisions),
 	}
 	return ds
 }
+func getShowName(labels map[string]string) string {
+	return firstLabelValue(labels, getShowLabelKeys())
+}
+
+func getShowLabelKeys() []string {
+	if ShowLabelKey == "" {
+		return ShowLabelKeys
+	}
+	if len(ShowLabelKeys) == 0 {
+		return []string{ShowLabelKey}
+	}
+	return append([]string{ShowLabelKey}, ShowLabelKeys[1:]...)
+}
+
 func buildResource(list []corev1.Container) Resouces {
 	rs := Resouces{
 		Requests: corev1.ResourceList{},
 		Limits:   corev1.ResourceList{},
 	}
 	for _, container := range list {
-		for rname, value := range container.Resources.Requests {
-			r, ok := rs.Requests[corev1.ResourceName(rname)]
-			if !ok {
-				r = resource.Quantity{}
-			}
-			r.Add(value)
-			rs.Requests[corev1.ResourceName(rname)] = r
-		}
-
-		for rname, value := range container.Resources.Limits {
-			r, ok := rs.Limits[corev1.ResourceName(rname)]
-			if !ok {
-				r = resource.Quantity{}
-			}
-			r.Add(value)
-			rs.Limits[corev1.ResourceName(rname)] = r
-		}
+		AddResourceList(rs.Requests, container.Resources.Requests)
+		AddResourceList(rs.Limits, container.Resources.Limits)
 	}
 	return rs
 }
@@ -222,7 +263,14 @@ func putCacheSummaryResource(clusterName string, sru SummaryResourceUseage) {
 	localCacheSummaryResource[clusterName] = sru
 }
 
-func getCacheSummaryResourceWithClusterName(clusterName string) (SummaryResourceUseage, bool) {
+func deleteCacheSummaryResource(clusterName string) {
+	deployLock.Lock()
+	defer deployLock.Unlock()
+
+	delete(localCacheSummaryResource, clusterName)
+}
+
+func GetCacheSummaryResourceWithClusterName(clusterName string) (SummaryResourceUseage, bool) {
 	deployLock.Lock()
 	defer deployLock.Unlock()
 
@@ -232,3 +280,17 @@ func getCacheSummaryResourceWithClusterName(clusterName string) (SummaryResource
 	sru, ok := localCacheSummaryResource[clusterName]
 	return sru, ok
 }
+
+func GetAllCacheSummaryResource() []SummaryResourceUseage {
+	deployLock.Lock()
+	defer deployLock.Unlock()
+
+	list := make([]SummaryResourceUseage, 0, len(localCacheSummaryResource))
+	for _, sru := range localCacheSummaryResource {
+		list = append(list, sru)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,27 @@
+package resource
+
+import "testing"
+
+func TestGetShowName(t *testing.T) {
+	defer func(key string, keys []string) { ShowLabelKey, ShowLabelKeys = key, keys }(ShowLabelKey, ShowLabelKeys)
+
+	labels := map[string]string{"app": "web", "app.kubernetes.io/name": "web-chart", "k8s-app": "web-legacy"}
+	tests := []struct {
+		name     string
+		key      string
+		keys     []string
+		expected string
+	}{
+		{"default keys", "", []string{"app", "app.kubernetes.io/name"}, "web"},
+		{"fallback key", "", []string{"name", "app.kubernetes.io/name"}, "web-chart"},
+		{"deprecated key replaces first", "k8s-app", []string{"app", "app.kubernetes.io/name"}, "web-legacy"},
+		{"deprecated key keeps fallback", "name", []string{"app", "app.kubernetes.io/name"}, "web-chart"},
+		{"deprecated key only", "k8s-app", nil, "web-legacy"},
+	}
+	for _, tt := range tests {
+		ShowLabelKey, ShowLabelKeys = tt.key, tt.keys
+		if name := getShowName(labels); name != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, name)
+		}
+	}
+}
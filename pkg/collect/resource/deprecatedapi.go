@@ -0,0 +1,147 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/symcn/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// UpgradeTargetVersion kubernetes version clusters plan to upgrade to, such as v1.25, default target of upgrade report
+	UpgradeTargetVersion = ""
+
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// RemovedAPI groupVersion resource removed in RemovedIn minor version
+type RemovedAPI struct {
+	GroupVersionResource schema.GroupVersionResource
+	RemovedIn            string
+	Replacement          string
+}
+
+// RemovedAPIs served-but-deprecated groupVersions and the release removed in
+var RemovedAPIs = []RemovedAPI{
+	{GroupVersionResource: gvr("extensions", "v1beta1", "deployments"), RemovedIn: "v1.16", Replacement: "apps/v1"},
+	{GroupVersionResource: gvr("extensions", "v1beta1", "daemonsets"), RemovedIn: "v1.16", Replacement: "apps/v1"},
+	{GroupVersionResource: gvr("extensions", "v1beta1", "replicasets"), RemovedIn: "v1.16", Replacement: "apps/v1"},
+	{GroupVersionResource: gvr("extensions", "v1beta1", "networkpolicies"), RemovedIn: "v1.16", Replacement: "networking.k8s.io/v1"},
+	{GroupVersionResource: gvr("apps", "v1beta1", "deployments"), RemovedIn: "v1.16", Replacement: "apps/v1"},
+	{GroupVersionResource: gvr("apps", "v1beta2", "deployments"), RemovedIn: "v1.16", Replacement: "apps/v1"},
+	{GroupVersionResource: gvr("extensions", "v1beta1", "ingresses"), RemovedIn: "v1.22", Replacement: "networking.k8s.io/v1"},
+	{GroupVersionResource: gvr("networking.k8s.io", "v1beta1", "ingresses"), RemovedIn: "v1.22", Replacement: "networking.k8s.io/v1"},
+	{GroupVersionResource: gvr("networking.k8s.io", "v1beta1", "ingressclasses"), RemovedIn: "v1.22", Replacement: "networking.k8s.io/v1"},
+	{GroupVersionResource: gvr("admissionregistration.k8s.io", "v1beta1", "mutatingwebhookconfigurations"), RemovedIn: "v1.22", Replacement: "admissionregistration.k8s.io/v1"},
+	{GroupVersionResource: gvr("admissionregistration.k8s.io", "v1beta1", "validatingwebhookconfigurations"), RemovedIn: "v1.22", Replacement: "admissionregistration.k8s.io/v1"},
+	{GroupVersionResource: gvr("apiextensions.k8s.io", "v1beta1", "customresourcedefinitions"), RemovedIn: "v1.22", Replacement: "apiextensions.k8s.io/v1"},
+	{GroupVersionResource: gvr("apiregistration.k8s.io", "v1beta1", "apiservices"), RemovedIn: "v1.22", Replacement: "apiregistration.k8s.io/v1"},
+	{GroupVersionResource: gvr("certificates.k8s.io", "v1beta1", "certificatesigningrequests"), RemovedIn: "v1.22", Replacement: "certificates.k8s.io/v1"},
+	{GroupVersionResource: gvr("coordination.k8s.io", "v1beta1", "leases"), RemovedIn: "v1.22", Replacement: "coordination.k8s.io/v1"},
+	{GroupVersionResource: gvr("rbac.authorization.k8s.io", "v1beta1", "clusterroles"), RemovedIn: "v1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{GroupVersionResource: gvr("rbac.authorization.k8s.io", "v1beta1", "clusterrolebindings"), RemovedIn: "v1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{GroupVersionResource: gvr("rbac.authorization.k8s.io", "v1beta1", "roles"), RemovedIn: "v1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{GroupVersionResource: gvr("rbac.authorization.k8s.io", "v1beta1", "rolebindings"), RemovedIn: "v1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{GroupVersionResource: gvr("scheduling.k8s.io", "v1beta1", "priorityclasses"), RemovedIn: "v1.22", Replacement: "scheduling.k8s.io/v1"},
+	{GroupVersionResource: gvr("storage.k8s.io", "v1beta1", "csidrivers"), RemovedIn: "v1.22", Replacement: "storage.k8s.io/v1"},
+	{GroupVersionResource: gvr("storage.k8s.io", "v1beta1", "storageclasses"), RemovedIn: "v1.22", Replacement: "storage.k8s.io/v1"},
+	{GroupVersionResource: gvr("storage.k8s.io", "v1beta1", "volumeattachments"), RemovedIn: "v1.22", Replacement: "storage.k8s.io/v1"},
+	{GroupVersionResource: gvr("batch", "v1beta1", "cronjobs"), RemovedIn: "v1.25", Replacement: "batch/v1"},
+	{GroupVersionResource: gvr("discovery.k8s.io", "v1beta1", "endpointslices"), RemovedIn: "v1.25", Replacement: "discovery.k8s.io/v1"},
+	{GroupVersionResource: gvr("events.k8s.io", "v1beta1", "events"), RemovedIn: "v1.25", Replacement: "events.k8s.io/v1"},
+	{GroupVersionResource: gvr("autoscaling", "v2beta1", "horizontalpodautoscalers"), RemovedIn: "v1.25", Replacement: "autoscaling/v2"},
+	{GroupVersionResource: gvr("policy", "v1beta1", "poddisruptionbudgets"), RemovedIn: "v1.25", Replacement: "policy/v1"},
+	{GroupVersionResource: gvr("policy", "v1beta1", "podsecuritypolicies"), RemovedIn: "v1.25", Replacement: "Pod Security Admission"},
+	{GroupVersionResource: gvr("node.k8s.io", "v1beta1", "runtimeclasses"), RemovedIn: "v1.25", Replacement: "node.k8s.io/v1"},
+	{GroupVersionResource: gvr("autoscaling", "v2beta2", "horizontalpodautoscalers"), RemovedIn: "v1.26", Replacement: "autoscaling/v2"},
+	{GroupVersionResource: gvr("flowcontrol.apiserver.k8s.io", "v1beta1", "flowschemas"), RemovedIn: "v1.26", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{GroupVersionResource: gvr("flowcontrol.apiserver.k8s.io", "v1beta1", "prioritylevelconfigurations"), RemovedIn: "v1.26", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{GroupVersionResource: gvr("storage.k8s.io", "v1beta1", "csistoragecapacities"), RemovedIn: "v1.27", Replacement: "storage.k8s.io/v1"},
+	{GroupVersionResource: gvr("flowcontrol.apiserver.k8s.io", "v1beta2", "flowschemas"), RemovedIn: "v1.29", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{GroupVersionResource: gvr("flowcontrol.apiserver.k8s.io", "v1beta2", "prioritylevelconfigurations"), RemovedIn: "v1.29", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+type DeprecatedAPIUsage struct {
+	APIVersion  string
+	Resource    string
+	Namespace   string
+	Name        string
+	RemovedIn   string
+	Replacement string
+}
+
+// getDeprecatedAPIUsages find objects still managed with api versions in RemovedAPIs, all removal releases
+// are collected so upgrade report can filter by any target. Only groupVersions served by discovery are checked.
+func getDeprecatedAPIUsages(cli api.MingleProxyClient) []DeprecatedAPIUsage {
+	usages := []DeprecatedAPIUsage{}
+	for _, removed := range RemovedAPIs {
+		if !isResourceServed(cli, removed.GroupVersionResource) {
+			continue
+		}
+
+		gv := removed.GroupVersionResource.GroupVersion().String()
+		list, err := cli.GetDynamicInterface().Resource(removed.GroupVersionResource).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			klog.Warningf("failed to list %s: %v", removed.GroupVersionResource.String(), err)
+			continue
+		}
+		for _, item := range list.Items {
+			if !isManagedWithAPIVersion(&item, gv) {
+				continue
+			}
+			usages = append(usages, DeprecatedAPIUsage{
+				APIVersion:  gv,
+				Resource:    removed.GroupVersionResource.Resource,
+				Namespace:   item.GetNamespace(),
+				Name:        item.GetName(),
+				RemovedIn:   removed.RemovedIn,
+				Replacement: removed.Replacement,
+			})
+		}
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		return a.APIVersion+a.Resource+a.Namespace+a.Name < b.APIVersion+b.Resource+b.Namespace+b.Name
+	})
+	return usages
+}
+
+func isResourceServed(cli api.MingleProxyClient, r schema.GroupVersionResource) bool {
+	resources, err := cli.GetKubeInterface().Discovery().ServerResourcesForGroupVersion(r.GroupVersion().String())
+	if err != nil {
+		return false
+	}
+	for _, res := range resources.APIResources {
+		if res.Name == r.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// isManagedWithAPIVersion object is written by client with apiVersion, from managedFields or last-applied-configuration
+func isManagedWithAPIVersion(obj *unstructured.Unstructured, apiVersion string) bool {
+	for _, mf := range obj.GetManagedFields() {
+		if mf.APIVersion == apiVersion {
+			return true
+		}
+	}
+	if data, ok := obj.GetAnnotations()[lastAppliedAnnotation]; ok {
+		applied := struct {
+			APIVersion string `json:"apiVersion"`
+		}{}
+		if err := json.Unmarshal([]byte(data), &applied); err == nil && applied.APIVersion == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+func gvr(group, version, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+}
@@ -0,0 +1,310 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/symcn/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	HealthStateHealthy     = "Healthy"
+	HealthStateDegraded    = "Degraded"
+	HealthStateUnhealthy   = "Unhealthy"
+	HealthStateUnreachable = "Unreachable"
+)
+
+var (
+	// HealthWeights weight of each part in health score, sum is 100
+	HealthWeights = map[string]float64{
+		"apiserver":      40,
+		"managedcluster": 20,
+		"nodes":          20,
+		"workloads":      20,
+	}
+	HealthyScore       = 90.0
+	DegradedScore      = 60.0
+	SlowProbeThreshold = time.Second
+	slowProbePenalty   = 10.0
+
+	healthLock              sync.Mutex
+	localCacheClusterHealth = map[string]ClusterHealth{}
+)
+
+type ClusterHealth struct {
+	ClusterName             string
+	State                   string
+	Score                   float64
+	Reasons                 []string
+	ProbeLatency            string
+	Readyz                  []HealthCheck
+	Livez                   []HealthCheck
+	ManagedClusterCondition map[string]string
+	// NodeStatusCollected false means cluster status not collected, nodes scored as not ready
+	NodeStatusCollected      bool
+	NodeReadyRatio           float64
+	WorkloadUnavailableRatio float64
+	LastProbeTime            time.Time
+}
+
+type HealthCheck struct {
+	Name    string
+	OK      bool
+	Message string
+}
+
+// CollectClusterHealth compute weighted health score with apiserver verbose checks, ManagedCluster conditions,
+// node readiness and workload availability, should invoke after CollectClusterStatus and CollectDeploymentStatus.
+func CollectClusterHealth(cli api.MingleProxyClient) {
+	clusterName := cli.GetClusterCfgInfo().GetName()
+	health := ClusterHealth{
+		ClusterName:             clusterName,
+		Reasons:                 []string{},
+		ManagedClusterCondition: map[string]string{},
+		LastProbeTime:           time.Now(),
+	}
+
+	start := time.Now()
+	readyz, readyOK, err := getVerboseHealthChecks(cli, "/readyz")
+	latency := time.Since(start)
+	health.ProbeLatency = latency.String()
+	if err != nil {
+		health.State = HealthStateUnreachable
+		health.Reasons = append(health.Reasons, fmt.Sprintf("probe /readyz failed: %v", err))
+		putCacheClusterHealth(clusterName, health)
+		klog.Warningf("cluster %s is unreachable: %v", clusterName, err)
+		return
+	}
+	livez, liveOK, err := getVerboseHealthChecks(cli, "/livez")
+	if err != nil {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("probe /livez failed: %v", err))
+	}
+	health.Readyz, health.Livez = readyz, livez
+
+	var score float64
+
+	// apiserver
+	if readyOK && liveOK {
+		score += HealthWeights["apiserver"]
+	} else {
+		for _, c := range append(readyz, livez...) {
+			if !c.OK {
+				health.Reasons = append(health.Reasons, fmt.Sprintf("apiserver check %s failed: %s", c.Name, c.Message))
+			}
+		}
+		if total := len(readyz) + len(livez); total > 0 {
+			score += HealthWeights["apiserver"] * float64(countPassed(readyz)+countPassed(livez)) / float64(total)
+		}
+	}
+
+	// managedcluster
+	available, reason := getManagedClusterConditions(clusterName, health.ManagedClusterCondition)
+	if available {
+		score += HealthWeights["managedcluster"]
+	} else {
+		health.Reasons = append(health.Reasons, reason)
+	}
+
+	// nodes
+	var cs *ClusterStatus
+	if status, ok := GetCacheClusterStatusWithClusterName(clusterName); ok {
+		cs = &status
+	}
+	var nodeReason string
+	health.NodeStatusCollected, health.NodeReadyRatio, nodeReason = getNodeReadyRatio(cs)
+	score += HealthWeights["nodes"] * health.NodeReadyRatio
+	if nodeReason != "" {
+		health.Reasons = append(health.Reasons, nodeReason)
+	}
+
+	// workloads
+	if sru, ok := GetCacheSummaryResourceWithClusterName(clusterName); ok {
+		health.WorkloadUnavailableRatio = getWorkloadUnavailableRatio(sru)
+	}
+	score += HealthWeights["workloads"] * (1 - health.WorkloadUnavailableRatio)
+	if health.WorkloadUnavailableRatio > 0 {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("workload unavailable ratio is %.2f", health.WorkloadUnavailableRatio))
+	}
+
+	if latency > SlowProbeThreshold {
+		score -= slowProbePenalty
+		health.Reasons = append(health.Reasons, fmt.Sprintf("probe latency %s exceeds %s", latency, SlowProbeThreshold))
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	health.Score = score
+	switch {
+	case score >= HealthyScore:
+		health.State = HealthStateHealthy
+	case score >= DegradedScore:
+		health.State = HealthStateDegraded
+	default:
+		health.State = HealthStateUnhealthy
+	}
+	putCacheClusterHealth(clusterName, health)
+
+	data, _ := json.Marshal(health)
+	klog.V(4).Infof("get cluster health:\n%s", string(data))
+}
+
+// getNodeReadyRatio unknown node status scores zero, a cluster without collected status
+// or without any node must not look healthy.
+func getNodeReadyRatio(cs *ClusterStatus) (collected bool, ratio float64, reason string) {
+	if cs == nil {
+		return false, 0, "node status not collected"
+	}
+	ns := cs.NodeStatistics
+	total := ns.ReadyNodes + ns.NotReadyNodes + ns.UnknownNodes + ns.LostNodes
+	if total == 0 {
+		return true, 0, "no node found"
+	}
+	ratio = float64(ns.ReadyNodes) / float64(total)
+	if ratio < 1 {
+		reason = fmt.Sprintf("node ready ratio is %.2f", ratio)
+	}
+	return true, ratio, reason
+}
+
+// getVerboseHealthChecks parse verbose output such as:
+// [+]ping ok
+// [-]etcd failed: reason withheld
+// readyz check failed
+func getVerboseHealthChecks(cli api.MingleProxyClient, path string) ([]HealthCheck, bool, error) {
+	var statusCode int
+	body, err := cli.GetKubeInterface().Discovery().RESTClient().Get().AbsPath(path).Param("verbose", "").Do(context.TODO()).StatusCode(&statusCode).Raw()
+	if statusCode == 0 {
+		return nil, false, err
+	}
+
+	checks := []HealthCheck{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		var ok bool
+		switch {
+		case strings.HasPrefix(line, "[+]"):
+			ok = true
+		case strings.HasPrefix(line, "[-]"):
+			ok = false
+		default:
+			continue
+		}
+		name, message := line[3:], ""
+		if i := strings.Index(name, " "); i >= 0 {
+			name, message = name[:i], strings.TrimSpace(name[i+1:])
+		}
+		checks = append(checks, HealthCheck{Name: name, OK: ok, Message: message})
+	}
+	return checks, statusCode == http.StatusOK, nil
+}
+
+func countPassed(checks []HealthCheck) int {
+	n := 0
+	for _, c := range checks {
+		if c.OK {
+			n++
+		}
+	}
+	return n
+}
+
+// getManagedClusterConditions fill conditions of ManagedCluster on hub, returns whether it's available
+func getManagedClusterConditions(clusterName string, conditions map[string]string) (bool, string) {
+	if kube.ManagerPlaneClusterClient == nil {
+		return false, "manager-plane client not initialized"
+	}
+	mc := &clusterapiv1.ManagedCluster{}
+	if err := kube.ManagerPlaneClusterClient.Get(types.NamespacedName{Name: clusterName}, mc); err != nil {
+		return false, fmt.Sprintf("get ManagedCluster %s failed: %v", clusterName, err)
+	}
+	for _, c := range mc.Status.Conditions {
+		conditions[c.Type] = string(c.Status)
+	}
+	if meta.IsStatusConditionTrue(mc.Status.Conditions, clusterapiv1.ManagedClusterConditionAvailable) {
+		return true, ""
+	}
+	return false, fmt.Sprintf("ManagedCluster condition %s is %s", clusterapiv1.ManagedClusterConditionAvailable, conditions[clusterapiv1.ManagedClusterConditionAvailable])
+}
+
+func getWorkloadUnavailableRatio(sru SummaryResourceUseage) float64 {
+	var desired, unavailable int32
+	for _, list := range sru.DeploymentStatistics.List {
+		for _, item := range list {
+			desired += item.Replicas
+			unavailable += item.UnavailableReplicas
+		}
+	}
+	for _, list := range sru.StatefulsetStatistics.List {
+		for _, item := range list {
+			desired += item.Replicas
+			unavailable += item.Replicas - item.ReadyReplicas
+		}
+	}
+	for _, list := range sru.DaemonsetStatistics.List {
+		for _, item := range list {
+			desired += item.DesiredNumberScheduled
+			unavailable += item.NumberUnavailable
+		}
+	}
+	if desired == 0 || unavailable <= 0 {
+		return 0
+	}
+	return float64(unavailable) / float64(desired)
+}
+
+func putCacheClusterHealth(clusterName string, health ClusterHealth) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	if len(localCacheClusterHealth) == 0 {
+		localCacheClusterHealth = map[string]ClusterHealth{}
+	}
+	localCacheClusterHealth[clusterName] = health
+}
+
+func deleteCacheClusterHealth(clusterName string) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	delete(localCacheClusterHealth, clusterName)
+}
+
+func GetCacheClusterHealthWithClusterName(clusterName string) (ClusterHealth, bool) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	if len(localCacheClusterHealth) == 0 {
+		return ClusterHealth{}, false
+	}
+	health, ok := localCacheClusterHealth[clusterName]
+	return health, ok
+}
+
+func GetAllCacheClusterHealth() []ClusterHealth {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	list := make([]ClusterHealth, 0, len(localCacheClusterHealth))
+	for _, health := range localCacheClusterHealth {
+		list = append(list, health)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
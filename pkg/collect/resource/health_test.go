@@ -0,0 +1,25 @@
+package resource
+
+import "testing"
+
+func TestGetNodeReadyRatio(t *testing.T) {
+	tests := []struct {
+		name      string
+		cs        *ClusterStatus
+		collected bool
+		ratio     float64
+		reason    string
+	}{
+		{"not collected", nil, false, 0, "node status not collected"},
+		{"no node", &ClusterStatus{}, true, 0, "no node found"},
+		{"all ready", &ClusterStatus{NodeStatistics: NodeStatistics{ReadyNodes: 3}}, true, 1, ""},
+		{"partly ready", &ClusterStatus{NodeStatistics: NodeStatistics{ReadyNodes: 3, NotReadyNodes: 1}}, true, 0.75, "node ready ratio is 0.75"},
+		{"unknown and lost", &ClusterStatus{NodeStatistics: NodeStatistics{ReadyNodes: 2, UnknownNodes: 1, LostNodes: 1}}, true, 0.5, "node ready ratio is 0.50"},
+	}
+	for _, tt := range tests {
+		collected, ratio, reason := getNodeReadyRatio(tt.cs)
+		if collected != tt.collected || ratio != tt.ratio || reason != tt.reason {
+			t.Errorf("%s: expected %v %v %q, got %v %v %q", tt.name, tt.collected, tt.ratio, tt.reason, collected, ratio, reason)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/symcn/api"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	ContainerTypeContainer = "container"
+	ContainerTypeInit      = "init"
+	ContainerTypeEphemeral = "ephemeral"
+)
+
+var (
+	imageLock                sync.Mutex
+	localCacheImageInventory = map[string]ClusterImageInventory{}
+)
+
+type ClusterImageInventory struct {
+	ClusterName string
+	Images      []ImageReference
+}
+
+// ImageReference image of one container in a workload, pods of the workload running
+// the same image and digest are merged.
+type ImageReference struct {
+	Namespace     string
+	WorkloadKind  string
+	WorkloadName  string
+	Container     string
+	ContainerType string
+	Image         string
+	Repository    string
+	Tag           string
+	// Digest resolved from container status imageID when not pinned in spec
+	Digest string
+	// Latest image uses :latest tag, Untagged image has neither tag nor digest which also means latest
+	Latest   bool
+	Untagged bool
+	Pods     int32
+}
+
+// CollectImageInventory record images of all containers, init containers and ephemeral containers
+// from pods, pods are attributed to the top-level workload through owner references.
+func CollectImageInventory(cli api.MingleProxyClient, pods *corev1.PodList) {
+	clusterName := cli.GetClusterCfgInfo().GetName()
+
+	if pods == nil {
+		klog.Warningf("skip image inventory of %s, pods not listed", clusterName)
+		return
+	}
+	owners := buildOwnerIndex(cli)
+
+	refs := map[string]*ImageReference{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		kind, name := owners.resolve(pod)
+
+		statuses := map[string]corev1.ContainerStatus{}
+		for _, list := range [][]corev1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+			for _, cs := range list {
+				statuses[cs.Name] = cs
+			}
+		}
+
+		add := func(containerType, container, image string) {
+			repository, tag, digest := ParseImageReference(image)
+			if digest == "" {
+				digest = parseImageIDDigest(statuses[container].ImageID)
+			}
+			key := strings.Join([]string{pod.Namespace, kind, name, containerType, container, image, digest}, "/")
+			if ref, ok := refs[key]; ok {
+				ref.Pods++
+				return
+			}
+			refs[key] = &ImageReference{
+				Namespace:     pod.Namespace,
+				WorkloadKind:  kind,
+				WorkloadName:  name,
+				Container:     container,
+				ContainerType: containerType,
+				Image:         image,
+				Repository:    repository,
+				Tag:           tag,
+				Digest:        digest,
+				Latest:        tag == "latest",
+				Untagged:      tag == "" && !strings.Contains(image, "@"),
+				Pods:          1,
+			}
+		}
+		for _, c := range pod.Spec.InitContainers {
+			add(ContainerTypeInit, c.Name, c.Image)
+		}
+		for _, c := range pod.Spec.Containers {
+			add(ContainerTypeContainer, c.Name, c.Image)
+		}
+		for _, c := range pod.Spec.EphemeralContainers {
+			add(ContainerTypeEphemeral, c.Name, c.Image)
+		}
+	}
+
+	inventory := ClusterImageInventory{
+		ClusterName: clusterName,
+		Images:      make([]ImageReference, 0, len(refs)),
+	}
+	for _, ref := range refs {
+		inventory.Images = append(inventory.Images, *ref)
+	}
+	sort.Slice(inventory.Images, func(i, j int) bool {
+		a, b := inventory.Images[i], inventory.Images[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.WorkloadKind+a.WorkloadName != b.WorkloadKind+b.WorkloadName {
+			return a.WorkloadKind+a.WorkloadName < b.WorkloadKind+b.WorkloadName
+		}
+		return a.Container+a.Digest < b.Container+b.Digest
+	})
+	putCacheImageInventory(clusterName, inventory)
+
+	data, _ := json.Marshal(inventory)
+	klog.V(4).Infof("get image inventory:\n%s", string(data))
+}
+
+// parseImageIDDigest parse digest from imageID such as:
+// docker-pullable://nginx@sha256:xxx
+// docker.io/library/nginx@sha256:xxx
+// sha256:xxx is local image id rather than registry digest, ignored.
+func parseImageIDDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return ""
+}
+
+// ownerIndex controller of ReplicaSet and Job, used to attribute pod to Deployment and CronJob
+type ownerIndex map[string]metav1.OwnerReference
+
+func buildOwnerIndex(cli api.MingleProxyClient) ownerIndex {
+	index := ownerIndex{}
+
+	replicasets := &appsv1.ReplicaSetList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), replicasets); err != nil {
+		klog.Warningf("failed to list replicasets: %v", err)
+	}
+	for _, rs := range replicasets.Items {
+		if ref := metav1.GetControllerOf(&rs); ref != nil {
+			index[KindReplicaSet+"/"+rs.Namespace+"/"+rs.Name] = *ref
+		}
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), jobs); err != nil {
+		klog.Warningf("failed to list jobs: %v", err)
+	}
+	for _, job := range jobs.Items {
+		if ref := metav1.GetControllerOf(&job); ref != nil {
+			index[KindJob+"/"+job.Namespace+"/"+job.Name] = *ref
+		}
+	}
+	return index
+}
+
+// resolve returns kind and name of top-level workload, bare pod returns Pod itself
+func (index ownerIndex) resolve(pod *corev1.Pod) (string, string) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return KindPod, pod.Name
+	}
+	kind, name := ref.Kind, ref.Name
+	// owner references are not validated by apiserver, stop at cycle such as A->B->A
+	visited := map[string]bool{}
+	for {
+		key := kind + "/" + pod.Namespace + "/" + name
+		parent, ok := index[key]
+		if !ok || visited[key] {
+			return kind, name
+		}
+		visited[key] = true
+		kind, name = parent.Kind, parent.Name
+	}
+}
+
+func putCacheImageInventory(clusterName string, inventory ClusterImageInventory) {
+	imageLock.Lock()
+	defer imageLock.Unlock()
+
+	if len(localCacheImageInventory) == 0 {
+		localCacheImageInventory = map[string]ClusterImageInventory{}
+	}
+	localCacheImageInventory[clusterName] = inventory
+}
+
+func deleteCacheImageInventory(clusterName string) {
+	imageLock.Lock()
+	defer imageLock.Unlock()
+
+	delete(localCacheImageInventory, clusterName)
+}
+
+func GetCacheImageInventoryWithClusterName(clusterName string) (ClusterImageInventory, bool) {
+	imageLock.Lock()
+	defer imageLock.Unlock()
+
+	if len(localCacheImageInventory) == 0 {
+		return ClusterImageInventory{}, false
+	}
+	inventory, ok := localCacheImageInventory[clusterName]
+	return inventory, ok
+}
+
+func GetAllCacheImageInventory() []ClusterImageInventory {
+	imageLock.Lock()
+	defer imageLock.Unlock()
+
+	list := make([]ClusterImageInventory, 0, len(localCacheImageInventory))
+	for _, inventory := range localCacheImageInventory {
+		list = append(list, inventory)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,41 @@
+package resource
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOwnerIndexResolve(t *testing.T) {
+	controller := true
+	ownedBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+	}
+	index := ownerIndex{
+		KindReplicaSet + "/default/web-5d4f": {Kind: "Deployment", Name: "web", Controller: &controller},
+		KindJob + "/default/backup-123":      {Kind: KindCronJob, Name: "backup", Controller: &controller},
+		KindJob + "/default/loop-a":          {Kind: KindJob, Name: "loop-b", Controller: &controller},
+		KindJob + "/default/loop-b":          {Kind: KindJob, Name: "loop-a", Controller: &controller},
+	}
+
+	tests := []struct {
+		name   string
+		owners []metav1.OwnerReference
+		kind   string
+		owner  string
+	}{
+		{"bare", nil, KindPod, "bare"},
+		{"deployment", ownedBy(KindReplicaSet, "web-5d4f"), "Deployment", "web"},
+		{"cronjob", ownedBy(KindJob, "backup-123"), KindCronJob, "backup"},
+		{"orphan replicaset", ownedBy(KindReplicaSet, "orphan"), KindReplicaSet, "orphan"},
+		{"cycle", ownedBy(KindJob, "loop-a"), KindJob, "loop-a"},
+	}
+	for _, tt := range tests {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: tt.name, OwnerReferences: tt.owners}}
+		kind, name := index.resolve(pod)
+		if kind != tt.kind || name != tt.owner {
+			t.Errorf("%s: resolve = %s/%s, want %s/%s", tt.name, kind, name, tt.kind, tt.owner)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/symcn/api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+var (
+	metricsNodesPath = "/apis/metrics.k8s.io/v1beta1/nodes"
+	metricsPodsPath  = "/apis/metrics.k8s.io/v1beta1/pods"
+)
+
+// nodeMetricsList and podMetricsList only decode the fields used in metrics.k8s.io/v1beta1,
+// avoid depend on k8s.io/metrics.
+type nodeMetricsList struct {
+	Items []struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Usage             corev1.ResourceList `json:"usage"`
+	} `json:"items"`
+}
+
+type podMetricsList struct {
+	Items []struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Containers        []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+type podUsage struct {
+	namespace string
+	labels    labels.Set
+	usage     corev1.ResourceList
+}
+
+// podUsageIndex pod live usage with labels, a nil index means metrics api is not available.
+type podUsageIndex struct {
+	pods []podUsage
+}
+
+// getNodeUsage return node live usage with node name, returns nil when metrics api is not available.
+func getNodeUsage(cli api.MingleProxyClient) map[string]corev1.ResourceList {
+	list := &nodeMetricsList{}
+	if ok := getMetrics(cli, metricsNodesPath, list); !ok {
+		return nil
+	}
+
+	result := make(map[string]corev1.ResourceList, len(list.Items))
+	for _, item := range list.Items {
+		result[item.Name] = item.Usage
+	}
+	return result
+}
+
+// buildPodUsageIndex returns nil when metrics api is not available or pods not listed.
+func buildPodUsageIndex(cli api.MingleProxyClient, pods *corev1.PodList) *podUsageIndex {
+	if pods == nil {
+		return nil
+	}
+	list := &podMetricsList{}
+	if ok := getMetrics(cli, metricsPodsPath, list); !ok {
+		return nil
+	}
+
+	podLabels := make(map[types.NamespacedName]map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		podLabels[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = pod.Labels
+	}
+
+	idx := &podUsageIndex{pods: make([]podUsage, 0, len(list.Items))}
+	for _, item := range list.Items {
+		usage := corev1.ResourceList{}
+		for _, container := range item.Containers {
+			AddResourceList(usage, container.Usage)
+		}
+		idx.pods = append(idx.pods, podUsage{
+			namespace: item.Namespace,
+			labels:    podLabels[types.NamespacedName{Namespace: item.Namespace, Name: item.Name}],
+			usage:     usage,
+		})
+	}
+	return idx
+}
+
+// attach sum usage of pods matched the workload selector, and compute usage/request
+// efficiency against per-pod requests times matched pods.
+func (idx *podUsageIndex) attach(rs *Resouces, namespace string, selector *metav1.LabelSelector) {
+	if idx == nil || selector == nil {
+		return
+	}
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil || sel.Empty() {
+		return
+	}
+
+	usage := corev1.ResourceList{}
+	var count int64
+	for _, pod := range idx.pods {
+		if pod.namespace != namespace || !sel.Matches(pod.labels) {
+			continue
+		}
+		AddResourceList(usage, pod.usage)
+		count++
+	}
+
+	rs.Usage = usage
+	rs.Efficiency = map[corev1.ResourceName]float64{}
+	for name, value := range usage {
+		request, ok := rs.Requests[name]
+		if !ok || count == 0 {
+			continue
+		}
+		total := request.AsApproximateFloat64() * float64(count)
+		if total <= 0 {
+			continue
+		}
+		rs.Efficiency[name] = value.AsApproximateFloat64() / total
+	}
+}
+
+// getMetrics query metrics.k8s.io through cluster proxy client,
+// returns false when metrics-server isn't installed or not ready.
+func getMetrics(cli api.MingleProxyClient, path string, v interface{}) bool {
+	var statusCode int
+	data, err := cli.GetKubeInterface().Discovery().RESTClient().Get().AbsPath(path).Do(context.TODO()).StatusCode(&statusCode).Raw()
+	if err != nil {
+		if apierrors.IsNotFound(err) || statusCode == http.StatusNotFound || statusCode == http.StatusServiceUnavailable {
+			klog.V(4).Infof("metrics api %s not available on cluster %s", path, cli.GetClusterCfgInfo().GetName())
+			return false
+		}
+		klog.Warningf("failed to query metrics api %s on cluster %s: %v", path, cli.GetClusterCfgInfo().GetName(), err)
+		return false
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		klog.Warningf("failed to decode metrics api %s response: %v", path, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,235 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/symcn/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// TeamLabelKey namespace label key used to group namespace for chargeback
+	TeamLabelKey = "team"
+	// OwnerAnnotationKeys namespace annotation keys recorded as owner information
+	OwnerAnnotationKeys = []string{"owner", "contact"}
+
+	namespaceLock             sync.Mutex
+	localCacheNamespaceStatus = map[string]ClusterNamespaceStatus{}
+)
+
+type ClusterNamespaceStatus struct {
+	ClusterName string
+	Namespaces  []NamespaceStatus
+}
+
+type NamespaceStatus struct {
+	Name             string
+	Team             string
+	Labels           map[string]string
+	OwnerAnnotations map[string]string
+	WorkloadCount    map[string]int
+	Pods             int32
+	Requests         corev1.ResourceList
+	Limits           corev1.ResourceList
+	ResourceQuotas   []ResourceQuotaStatus
+	LimitRanges      []LimitRangeStatus
+}
+
+type ResourceQuotaStatus struct {
+	Name string
+	Hard corev1.ResourceList
+	Used corev1.ResourceList
+}
+
+type LimitRangeStatus struct {
+	Name                 string
+	Type                 corev1.LimitType
+	Default              corev1.ResourceList
+	DefaultRequest       corev1.ResourceList
+	Max                  corev1.ResourceList
+	Min                  corev1.ResourceList
+	MaxLimitRequestRatio corev1.ResourceList
+}
+
+// CollectNamespaceStatus rollup requests and limits of active pods, quota and limitrange by namespace,
+// workload count is read from summary cache, so should invoke after CollectDeploymentStatus.
+func CollectNamespaceStatus(cli api.MingleProxyClient, pods *corev1.PodList) {
+	clusterName := cli.GetClusterCfgInfo().GetName()
+	// keep previous cache on any list failure, partial data would roll up as zero
+	if pods == nil {
+		klog.Warningf("skip namespace status of %s, pods not listed", clusterName)
+		return
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	err := cli.GetRuntimeClient().List(context.TODO(), namespaces)
+	if err != nil {
+		klog.Warningf("failed to list namespaces: %v", err)
+		return
+	}
+
+	nsMap := make(map[string]*NamespaceStatus, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		nsMap[ns.Name] = &NamespaceStatus{
+			Name:             ns.Name,
+			Team:             ns.Labels[TeamLabelKey],
+			Labels:           ns.Labels,
+			OwnerAnnotations: getOwnerAnnotations(ns.Annotations),
+			WorkloadCount:    map[string]int{},
+			Requests:         corev1.ResourceList{},
+			Limits:           corev1.ResourceList{},
+			ResourceQuotas:   []ResourceQuotaStatus{},
+			LimitRanges:      []LimitRangeStatus{},
+		}
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		ns, ok := nsMap[pod.Namespace]
+		if !ok || !isPodScheduledAndActive(pod) {
+			continue
+		}
+		requests, limits := podRequestsAndLimits(pod)
+		AddResourceList(ns.Requests, requests)
+		AddResourceList(ns.Limits, limits)
+		ns.Pods++
+	}
+
+	quotas := &corev1.ResourceQuotaList{}
+	if err = cli.GetRuntimeClient().List(context.TODO(), quotas); err != nil {
+		klog.Warningf("failed to list resourcequotas: %v", err)
+		return
+	}
+	for _, quota := range quotas.Items {
+		if ns, ok := nsMap[quota.Namespace]; ok {
+			ns.ResourceQuotas = append(ns.ResourceQuotas, ResourceQuotaStatus{
+				Name: quota.Name,
+				Hard: quota.Status.Hard,
+				Used: quota.Status.Used,
+			})
+		}
+	}
+
+	limitRanges := &corev1.LimitRangeList{}
+	if err = cli.GetRuntimeClient().List(context.TODO(), limitRanges); err != nil {
+		klog.Warningf("failed to list limitranges: %v", err)
+		return
+	}
+	for _, lr := range limitRanges.Items {
+		ns, ok := nsMap[lr.Namespace]
+		if !ok {
+			continue
+		}
+		for _, item := range lr.Spec.Limits {
+			ns.LimitRanges = append(ns.LimitRanges, LimitRangeStatus{
+				Name:                 lr.Name,
+				Type:                 item.Type,
+				Default:              item.Default,
+				DefaultRequest:       item.DefaultRequest,
+				Max:                  item.Max,
+				Min:                  item.Min,
+				MaxLimitRequestRatio: item.MaxLimitRequestRatio,
+			})
+		}
+	}
+
+	if sru, ok := GetCacheSummaryResourceWithClusterName(clusterName); ok {
+		countNamespaceWorkloads(nsMap, sru)
+	}
+
+	status := ClusterNamespaceStatus{
+		ClusterName: clusterName,
+		Namespaces:  make([]NamespaceStatus, 0, len(nsMap)),
+	}
+	for _, ns := range nsMap {
+		status.Namespaces = append(status.Namespaces, *ns)
+	}
+	sort.Slice(status.Namespaces, func(i, j int) bool {
+		return status.Namespaces[i].Name < status.Namespaces[j].Name
+	})
+	putCacheNamespaceStatus(clusterName, status)
+
+	data, _ := json.Marshal(status)
+	klog.V(4).Infof("get namespace status:\n%s", string(data))
+}
+
+func countNamespaceWorkloads(nsMap map[string]*NamespaceStatus, sru SummaryResourceUseage) {
+	count := func(kind, namespace string, n int) {
+		if ns, ok := nsMap[namespace]; ok && n > 0 {
+			ns.WorkloadCount[kind] += n
+		}
+	}
+	for namespace, list := range sru.DeploymentStatistics.List {
+		count("Deployment", namespace, len(list))
+	}
+	for namespace, list := range sru.StatefulsetStatistics.List {
+		count("StatefulSet", namespace, len(list))
+	}
+	for namespace, list := range sru.DaemonsetStatistics.List {
+		count("DaemonSet", namespace, len(list))
+	}
+	for kind, statistics := range sru.Workloads {
+		if kind == KindHorizontalPodAutoscaler {
+			continue
+		}
+		for namespace, list := range statistics.List {
+			count(kind, namespace, len(list))
+		}
+	}
+}
+
+func getOwnerAnnotations(annotations map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, key := range OwnerAnnotationKeys {
+		if value, ok := annotations[key]; ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func putCacheNamespaceStatus(clusterName string, status ClusterNamespaceStatus) {
+	namespaceLock.Lock()
+	defer namespaceLock.Unlock()
+
+	if len(localCacheNamespaceStatus) == 0 {
+		localCacheNamespaceStatus = map[string]ClusterNamespaceStatus{}
+	}
+	localCacheNamespaceStatus[clusterName] = status
+}
+
+func deleteCacheNamespaceStatus(clusterName string) {
+	namespaceLock.Lock()
+	defer namespaceLock.Unlock()
+
+	delete(localCacheNamespaceStatus, clusterName)
+}
+
+func GetCacheNamespaceStatusWithClusterName(clusterName string) (ClusterNamespaceStatus, bool) {
+	namespaceLock.Lock()
+	defer namespaceLock.Unlock()
+
+	if len(localCacheNamespaceStatus) == 0 {
+		return ClusterNamespaceStatus{}, false
+	}
+	status, ok := localCacheNamespaceStatus[clusterName]
+	return status, ok
+}
+
+func GetAllCacheNamespaceStatus() []ClusterNamespaceStatus {
+	namespaceLock.Lock()
+	defer namespaceLock.Unlock()
+
+	list := make([]ClusterNamespaceStatus, 0, len(localCacheNamespaceStatus))
+	for _, status := range localCacheNamespaceStatus {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var (
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+	nodeRoleLabel       = "kubernetes.io/role"
+	instanceTypeLabels  = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"}
+
+	inventoryConditionTypes = []corev1.NodeConditionType{
+		corev1.NodeReady,
+		corev1.NodeMemoryPressure,
+		corev1.NodeDiskPressure,
+		corev1.NodePIDPressure,
+	}
+)
+
+type NodeInfo struct {
+	Name                    string
+	Labels                  map[string]string
+	Roles                   []string
+	KubeletVersion          string
+	ContainerRuntimeVersion string
+	OSImage                 string
+	KernelVersion           string
+	Architecture            string
+	Zone                    string
+	InstanceType            string
+	Unschedulable           bool
+	Taints                  []corev1.Taint
+	Conditions              map[corev1.NodeConditionType]corev1.ConditionStatus
+	CreationTimestamp       time.Time
+	Age                     string
+	Capacity                corev1.ResourceList
+	Allocatable             corev1.ResourceList
+	Usage                   corev1.ResourceList
+}
+
+// getNodeInventory build node info list, nodeUsage is nil when metrics api is not available.
+func getNodeInventory(nodes *corev1.NodeList, nodeUsage map[string]corev1.ResourceList) []NodeInfo {
+	list := make([]NodeInfo, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		info := buildNodeInfo(&nodes.Items[i])
+		info.Usage = nodeUsage[info.Name]
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func buildNodeInfo(node *corev1.Node) NodeInfo {
+	info := NodeInfo{
+		Name:                    node.Name,
+		Labels:                  node.Labels,
+		Roles:                   getNodeRoles(node),
+		KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+		ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+		OSImage:                 node.Status.NodeInfo.OSImage,
+		KernelVersion:           node.Status.NodeInfo.KernelVersion,
+		Architecture:            node.Status.NodeInfo.Architecture,
+		Zone:                    firstLabelValue(node.Labels, zoneLabels),
+		InstanceType:            firstLabelValue(node.Labels, instanceTypeLabels),
+		Unschedulable:           node.Spec.Unschedulable,
+		Taints:                  node.Spec.Taints,
+		Conditions:              map[corev1.NodeConditionType]corev1.ConditionStatus{},
+		CreationTimestamp:       node.CreationTimestamp.Time,
+		Age:                     duration.HumanDuration(time.Since(node.CreationTimestamp.Time)),
+		Capacity:                node.Status.Capacity,
+		Allocatable:             node.Status.Allocatable,
+	}
+
+	for _, conditionType := range inventoryConditionTypes {
+		flag, condition := getNodeCondition(&node.Status, conditionType)
+		if flag == -1 {
+			info.Conditions[conditionType] = corev1.ConditionUnknown
+			continue
+		}
+		info.Conditions[conditionType] = condition.Status
+	}
+	return info
+}
+
+func getNodeRoles(node *corev1.Node) []string {
+	set := map[string]struct{}{}
+	for key, value := range node.Labels {
+		switch {
+		case strings.HasPrefix(key, nodeRoleLabelPrefix):
+			if role := strings.TrimPrefix(key, nodeRoleLabelPrefix); role != "" {
+				set[role] = struct{}{}
+			}
+		case key == nodeRoleLabel && value != "":
+			set[value] = struct{}{}
+		}
+	}
+
+	roles := make([]string, 0, len(set))
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
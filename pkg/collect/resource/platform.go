@@ -0,0 +1,199 @@
+package resource
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/symcn/api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DistributionEKS       = "eks"
+	DistributionGKE       = "gke"
+	DistributionAKS       = "aks"
+	DistributionOpenShift = "openshift"
+	DistributionK3s       = "k3s"
+	DistributionRKE2      = "rke2"
+	DistributionKind      = "kind"
+	DistributionMinikube  = "minikube"
+	DistributionKubeadm   = "kubeadm"
+	DistributionUnknown   = "unknown"
+)
+
+var (
+	// cloudProviders map node spec.providerID scheme to cloud provider
+	cloudProviders = map[string]string{
+		"aws":          "aws",
+		"gce":          "gcp",
+		"azure":        "azure",
+		"openstack":    "openstack",
+		"vsphere":      "vsphere",
+		"alicloud":     "alibaba",
+		"ibm":          "ibm",
+		"digitalocean": "digitalocean",
+	}
+
+	regionLabels = []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"}
+	zoneLabels   = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+)
+
+type DistributionInfo struct {
+	Distribution  string
+	CloudProvider string
+	Region        string
+	Zones         []string
+}
+
+// detectDistribution detect kubernetes distribution with node providerID and labels,
+// version string, well-known namespaces and api groups.
+func detectDistribution(cli api.MingleProxyClient, nodes *corev1.NodeList, clusterVersion *version.Info) DistributionInfo {
+	info := DistributionInfo{
+		Distribution: DistributionUnknown,
+		Zones:        []string{},
+	}
+
+	zones := map[string]struct{}{}
+	providerIDScheme := ""
+	for _, node := range nodes.Items {
+		if providerIDScheme == "" && strings.Contains(node.Spec.ProviderID, "://") {
+			providerIDScheme = strings.SplitN(node.Spec.ProviderID, "://", 2)[0]
+		}
+		if info.Region == "" {
+			info.Region = firstLabelValue(node.Labels, regionLabels)
+		}
+		if zone := firstLabelValue(node.Labels, zoneLabels); zone != "" {
+			zones[zone] = struct{}{}
+		}
+	}
+	for zone := range zones {
+		info.Zones = append(info.Zones, zone)
+	}
+	sort.Strings(info.Zones)
+
+	info.CloudProvider = cloudProviders[providerIDScheme]
+
+	gitVersion := ""
+	if clusterVersion != nil {
+		gitVersion = clusterVersion.GitVersion
+	}
+	info.Distribution = detectDistributionName(cli, nodes, providerIDScheme, gitVersion)
+
+	switch info.Distribution {
+	case DistributionEKS:
+		info.CloudProvider = "aws"
+	case DistributionGKE:
+		info.CloudProvider = "gcp"
+	case DistributionAKS:
+		info.CloudProvider = "azure"
+	}
+	return info
+}
+
+func detectDistributionName(cli api.MingleProxyClient, nodes *corev1.NodeList, providerIDScheme, gitVersion string) string {
+	// version string
+	switch {
+	case strings.Contains(gitVersion, "-eks-"):
+		return DistributionEKS
+	case strings.Contains(gitVersion, "-gke."):
+		return DistributionGKE
+	case strings.Contains(gitVersion, "+k3s"):
+		return DistributionK3s
+	case strings.Contains(gitVersion, "+rke2"):
+		return DistributionRKE2
+	}
+
+	// node labels
+	for _, node := range nodes.Items {
+		for key, value := range node.Labels {
+			switch {
+			case strings.HasPrefix(key, "eks.amazonaws.com/"):
+				return DistributionEKS
+			case strings.HasPrefix(key, "cloud.google.com/gke-"):
+				return DistributionGKE
+			case strings.HasPrefix(key, "kubernetes.azure.com/"):
+				return DistributionAKS
+			case strings.HasPrefix(key, "node.openshift.io/"):
+				return DistributionOpenShift
+			case strings.HasPrefix(key, "minikube.k8s.io/"):
+				return DistributionMinikube
+			case key == "node.kubernetes.io/instance-type" && value == "k3s":
+				return DistributionK3s
+			case key == "node.kubernetes.io/instance-type" && value == "rke2":
+				return DistributionRKE2
+			}
+		}
+	}
+
+	// node providerID
+	switch providerIDScheme {
+	case "kind":
+		return DistributionKind
+	case "k3s":
+		return DistributionK3s
+	case "rke2":
+		return DistributionRKE2
+	}
+	if providerIDScheme == "azure" && hasNodeNamePrefix(nodes, "aks-") {
+		return DistributionAKS
+	}
+
+	// api groups
+	if hasAPIGroupSuffix(cli, ".openshift.io") || hasNamespace(cli, "openshift-apiserver") {
+		return DistributionOpenShift
+	}
+
+	// kubeadm init upload kubeadm-config configmap
+	if hasConfigMap(cli, "kube-system", "kubeadm-config") {
+		return DistributionKubeadm
+	}
+	return DistributionUnknown
+}
+
+func firstLabelValue(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value, ok := labels[key]; ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func hasNodeNamePrefix(nodes *corev1.NodeList, prefix string) bool {
+	for _, node := range nodes.Items {
+		if strings.HasPrefix(node.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNamespace(cli api.MingleProxyClient, name string) bool {
+	ns := &corev1.Namespace{}
+	err := cli.GetRuntimeClient().Get(context.TODO(), types.NamespacedName{Name: name}, ns)
+	return err == nil
+}
+
+func hasConfigMap(cli api.MingleProxyClient, namespace, name string) bool {
+	cm := &corev1.ConfigMap{}
+	err := cli.GetRuntimeClient().Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, cm)
+	return err == nil
+}
+
+func hasAPIGroupSuffix(cli api.MingleProxyClient, suffix string) bool {
+	groups, err := cli.GetKubeInterface().Discovery().ServerGroups()
+	if err != nil {
+		klog.Warningf("failed to discover api groups: %v", err)
+		return false
+	}
+	for _, group := range groups.Groups {
+		if strings.HasSuffix(group.Name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/symcn/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	rtfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeProxyClient MingleProxyClient backed by fake clients
+type fakeProxyClient struct {
+	api.MingleProxyClient
+	rtClient      client.Client
+	kubeInterface kubernetes.Interface
+}
+
+func (f *fakeProxyClient) GetRuntimeClient() client.Client {
+	return f.rtClient
+}
+
+func (f *fakeProxyClient) GetKubeInterface() kubernetes.Interface {
+	return f.kubeInterface
+}
+
+func newFakeProxyClient(groupVersions []string, objs ...client.Object) *fakeProxyClient {
+	kubeInterface := kubefake.NewSimpleClientset()
+	discovery := kubeInterface.Discovery().(*fakediscovery.FakeDiscovery)
+	for _, gv := range groupVersions {
+		discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: gv})
+	}
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	return &fakeProxyClient{
+		rtClient:      rtfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		kubeInterface: kubeInterface,
+	}
+}
+
+func TestDetectDistributionName(t *testing.T) {
+	node := func(name string, labels map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	tests := []struct {
+		name             string
+		nodes            []corev1.Node
+		providerIDScheme string
+		gitVersion       string
+		groupVersions    []string
+		objs             []client.Object
+		expected         string
+	}{
+		{name: "eks version", gitVersion: "v1.23.7-eks-4721010", providerIDScheme: "aws", expected: DistributionEKS},
+		{name: "eks node label", nodes: []corev1.Node{node("ip-10-0-0-1", map[string]string{"eks.amazonaws.com/nodegroup": "ng-1"})}, gitVersion: "v1.23.7", expected: DistributionEKS},
+		{name: "gke version", gitVersion: "v1.23.8-gke.1900", providerIDScheme: "gce", expected: DistributionGKE},
+		{name: "gke node label", nodes: []corev1.Node{node("gke-pool-1", map[string]string{"cloud.google.com/gke-nodepool": "pool-1"})}, expected: DistributionGKE},
+		{name: "aks node label", nodes: []corev1.Node{node("aks-pool-1", map[string]string{"kubernetes.azure.com/cluster": "mc_rg"})}, expected: DistributionAKS},
+		{name: "aks node name", nodes: []corev1.Node{node("aks-pool-1", nil)}, providerIDScheme: "azure", expected: DistributionAKS},
+		{name: "azure not aks", nodes: []corev1.Node{node("vm-1", nil)}, providerIDScheme: "azure", expected: DistributionUnknown},
+		{name: "k3s version", gitVersion: "v1.23.6+k3s1", expected: DistributionK3s},
+		{name: "k3s instance type", nodes: []corev1.Node{node("node-1", map[string]string{"node.kubernetes.io/instance-type": "k3s"})}, expected: DistributionK3s},
+		{name: "k3s provider id", providerIDScheme: "k3s", expected: DistributionK3s},
+		{name: "openshift node label", nodes: []corev1.Node{node("master-0", map[string]string{"node.openshift.io/os_id": "rhcos"})}, expected: DistributionOpenShift},
+		{name: "openshift api group", groupVersions: []string{"v1", "route.openshift.io/v1"}, expected: DistributionOpenShift},
+		{name: "openshift namespace", objs: []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-apiserver"}}}, expected: DistributionOpenShift},
+		{name: "kubeadm", objs: []client.Object{&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kubeadm-config"}}}, expected: DistributionKubeadm},
+		{name: "unknown", nodes: []corev1.Node{node("node-1", map[string]string{"kubernetes.io/os": "linux"})}, gitVersion: "v1.23.4", groupVersions: []string{"v1", "apps/v1"}, expected: DistributionUnknown},
+	}
+	for _, tt := range tests {
+		cli := newFakeProxyClient(tt.groupVersions, tt.objs...)
+		name := detectDistributionName(cli, &corev1.NodeList{Items: tt.nodes}, tt.providerIDScheme, tt.gitVersion)
+		if name != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, name)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/symcn/api"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	RBACRiskClusterAdmin    = "cluster-admin"
+	RBACRiskWildcard        = "wildcard"
+	RBACRiskSecretsRead     = "secrets-read"
+	RBACRiskEscalate        = "escalate"
+	RBACRiskBind            = "bind"
+	RBACRiskImpersonate     = "impersonate"
+	RBACRiskAnonymous       = "anonymous"
+	anonymousUser           = "system:anonymous"
+	unauthenticatedGroup    = "system:unauthenticated"
+	clusterAdminClusterRole = "cluster-admin"
+)
+
+var (
+	// RBACIgnoreSubjectPrefixes subjects of control plane components are not reported,
+	// anonymous and unauthenticated bindings are always reported.
+	RBACIgnoreSubjectPrefixes = []string{"User:system:kube-", "User:system:node:", "Group:system:nodes", "Group:system:masters", "ServiceAccount:kube-system/"}
+
+	rbacLock               sync.Mutex
+	localCacheRBACExposure = map[string]ClusterRBACExposure{}
+)
+
+type ClusterRBACExposure struct {
+	ClusterName string
+	// RiskCount risk -> count of subjects hold it
+	RiskCount map[string]int
+	Subjects  []SubjectExposure
+	Grants    []RiskyGrant
+}
+
+// SubjectExposure risks hold by one subject through all bindings
+type SubjectExposure struct {
+	// Subject is Kind:name, service account is ServiceAccount:namespace/name
+	Subject  string
+	Risks    []string
+	Bindings []string
+}
+
+type RiskyGrant struct {
+	Subject string
+	// Binding is ClusterRoleBinding/name or RoleBinding/namespace/name
+	Binding string
+	Role    string
+	// Namespace empty means cluster-wide
+	Namespace string
+	Risks     []string
+}
+
+// CollectRBACExposure find risky grants of ClusterRoleBindings and RoleBindings
+func CollectRBACExposure(cli api.MingleProxyClient) {
+	clusterName := cli.GetClusterCfgInfo().GetName()
+
+	clusterRoles := &rbacv1.ClusterRoleList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), clusterRoles); err != nil {
+		klog.Warningf("failed to list clusterroles: %v", err)
+		return
+	}
+	roles := &rbacv1.RoleList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), roles); err != nil {
+		klog.Warningf("failed to list roles: %v", err)
+		return
+	}
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), clusterRoleBindings); err != nil {
+		klog.Warningf("failed to list clusterrolebindings: %v", err)
+		return
+	}
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), roleBindings); err != nil {
+		klog.Warningf("failed to list rolebindings: %v", err)
+		return
+	}
+
+	roleRisks := map[string][]string{}
+	for _, cr := range clusterRoles.Items {
+		risks := getRuleRisks(cr.Rules)
+		if cr.Name == clusterAdminClusterRole {
+			risks = append([]string{RBACRiskClusterAdmin}, risks...)
+		}
+		roleRisks["ClusterRole/"+cr.Name] = risks
+	}
+	for _, role := range roles.Items {
+		roleRisks["Role/"+role.Namespace+"/"+role.Name] = getRuleRisks(role.Rules)
+	}
+
+	grants := []RiskyGrant{}
+	for _, crb := range clusterRoleBindings.Items {
+		grants = appendRiskyGrants(grants, "ClusterRoleBinding/"+crb.Name, "", crb.RoleRef, crb.Subjects, roleRisks)
+	}
+	for _, rb := range roleBindings.Items {
+		grants = appendRiskyGrants(grants, "RoleBinding/"+rb.Namespace+"/"+rb.Name, rb.Namespace, rb.RoleRef, rb.Subjects, roleRisks)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].Subject != grants[j].Subject {
+			return grants[i].Subject < grants[j].Subject
+		}
+		return grants[i].Binding < grants[j].Binding
+	})
+
+	exposure := ClusterRBACExposure{
+		ClusterName: clusterName,
+		RiskCount:   map[string]int{},
+		Subjects:    buildSubjectExposure(grants),
+		Grants:      grants,
+	}
+	for _, subject := range exposure.Subjects {
+		for _, risk := range subject.Risks {
+			exposure.RiskCount[risk]++
+		}
+	}
+	putCacheRBACExposure(clusterName, exposure)
+
+	data, _ := json.Marshal(exposure)
+	klog.V(4).Infof("get rbac exposure:\n%s", string(data))
+}
+
+func appendRiskyGrants(grants []RiskyGrant, binding, namespace string, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject, roleRisks map[string][]string) []RiskyGrant {
+	role := roleRef.Kind + "/" + roleRef.Name
+	if roleRef.Kind == "Role" {
+		role = roleRef.Kind + "/" + namespace + "/" + roleRef.Name
+	}
+	risks := roleRisks[role]
+
+	for _, s := range subjects {
+		subject := formatSubject(s, namespace)
+		subjectRisks := risks
+		if isAnonymousSubject(s) {
+			subjectRisks = append([]string{RBACRiskAnonymous}, risks...)
+		} else if isIgnoredSubject(subject) {
+			continue
+		}
+		if len(subjectRisks) == 0 {
+			continue
+		}
+		grants = append(grants, RiskyGrant{
+			Subject:   subject,
+			Binding:   binding,
+			Role:      role,
+			Namespace: namespace,
+			Risks:     subjectRisks,
+		})
+	}
+	return grants
+}
+
+// isAnonymousSubject only User system:anonymous and Group system:unauthenticated,
+// ServiceAccount with the same name is an ordinary subject.
+func isAnonymousSubject(s rbacv1.Subject) bool {
+	return (s.Kind == rbacv1.UserKind && s.Name == anonymousUser) ||
+		(s.Kind == rbacv1.GroupKind && s.Name == unauthenticatedGroup)
+}
+
+// getRuleRisks returns risks granted by rules in fixed order
+func getRuleRisks(rules []rbacv1.PolicyRule) []string {
+	found := map[string]bool{}
+	for _, rule := range rules {
+		verbs := toSet(rule.Verbs)
+		resources := toSet(rule.Resources)
+		coreGroup := containsString(rule.APIGroups, "") || containsString(rule.APIGroups, "*")
+
+		if verbs["*"] || resources["*"] {
+			found[RBACRiskWildcard] = true
+		}
+		if coreGroup && (resources["secrets"] || resources["*"]) && (verbs["get"] || verbs["list"] || verbs["watch"] || verbs["*"]) {
+			found[RBACRiskSecretsRead] = true
+		}
+		for _, risk := range []string{RBACRiskEscalate, RBACRiskBind, RBACRiskImpersonate} {
+			if verbs[risk] || verbs["*"] {
+				found[risk] = true
+			}
+		}
+	}
+
+	risks := []string{}
+	for _, risk := range []string{RBACRiskWildcard, RBACRiskSecretsRead, RBACRiskEscalate, RBACRiskBind, RBACRiskImpersonate} {
+		if found[risk] {
+			risks = append(risks, risk)
+		}
+	}
+	return risks
+}
+
+func buildSubjectExposure(grants []RiskyGrant) []SubjectExposure {
+	subjects := map[string]*SubjectExposure{}
+	for _, g := range grants {
+		se, ok := subjects[g.Subject]
+		if !ok {
+			se = &SubjectExposure{Subject: g.Subject, Risks: []string{}, Bindings: []string{}}
+			subjects[g.Subject] = se
+		}
+		for _, risk := range g.Risks {
+			if !containsString(se.Risks, risk) {
+				se.Risks = append(se.Risks, risk)
+			}
+		}
+		se.Bindings = append(se.Bindings, g.Binding)
+	}
+
+	result := make([]SubjectExposure, 0, len(subjects))
+	for _, se := range subjects {
+		sort.Strings(se.Risks)
+		result = append(result, *se)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Subject < result[j].Subject
+	})
+	return result
+}
+
+// formatSubject service account namespace defaults to the namespace of RoleBinding
+func formatSubject(s rbacv1.Subject, bindingNamespace string) string {
+	if s.Kind == rbacv1.ServiceAccountKind {
+		namespace := s.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		return s.Kind + ":" + namespace + "/" + s.Name
+	}
+	return s.Kind + ":" + s.Name
+}
+
+func isIgnoredSubject(subject string) bool {
+	for _, prefix := range RBACIgnoreSubjectPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[strings.ToLower(item)] = true
+	}
+	return set
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func putCacheRBACExposure(clusterName string, exposure ClusterRBACExposure) {
+	rbacLock.Lock()
+	defer rbacLock.Unlock()
+
+	if len(localCacheRBACExposure) == 0 {
+		localCacheRBACExposure = map[string]ClusterRBACExposure{}
+	}
+	localCacheRBACExposure[clusterName] = exposure
+}
+
+func deleteCacheRBACExposure(clusterName string) {
+	rbacLock.Lock()
+	defer rbacLock.Unlock()
+
+	delete(localCacheRBACExposure, clusterName)
+}
+
+func GetCacheRBACExposureWithClusterName(clusterName string) (ClusterRBACExposure, bool) {
+	rbacLock.Lock()
+	defer rbacLock.Unlock()
+
+	if len(localCacheRBACExposure) == 0 {
+		return ClusterRBACExposure{}, false
+	}
+	exposure, ok := localCacheRBACExposure[clusterName]
+	return exposure, ok
+}
+
+func GetAllCacheRBACExposure() []ClusterRBACExposure {
+	rbacLock.Lock()
+	defer rbacLock.Unlock()
+
+	list := make([]ClusterRBACExposure, 0, len(localCacheRBACExposure))
+	for _, exposure := range localCacheRBACExposure {
+		list = append(list, exposure)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
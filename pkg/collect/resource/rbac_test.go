@@ -0,0 +1,33 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestAppendRiskyGrants(t *testing.T) {
+	roleRef := rbacv1.RoleRef{Kind: "ClusterRole", Name: "view"}
+	tests := []struct {
+		name    string
+		subject rbacv1.Subject
+		risks   []string
+	}{
+		{"anonymous user", rbacv1.Subject{Kind: rbacv1.UserKind, Name: anonymousUser}, []string{RBACRiskAnonymous}},
+		{"unauthenticated group", rbacv1.Subject{Kind: rbacv1.GroupKind, Name: unauthenticatedGroup}, []string{RBACRiskAnonymous}},
+		{"service account named anonymous", rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: anonymousUser, Namespace: "default"}, nil},
+		{"user named unauthenticated", rbacv1.Subject{Kind: rbacv1.UserKind, Name: unauthenticatedGroup}, nil},
+		{"ordinary user", rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"}, nil},
+	}
+	for _, tt := range tests {
+		grants := appendRiskyGrants(nil, "ClusterRoleBinding/test", "", roleRef, []rbacv1.Subject{tt.subject}, map[string][]string{})
+		var risks []string
+		if len(grants) > 0 {
+			risks = grants[0].Risks
+		}
+		if !reflect.DeepEqual(risks, tt.risks) {
+			t.Errorf("%s: expected risks %v, got %v", tt.name, tt.risks, risks)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package resource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/symcn/api"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+var (
+	deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+type RolloutStatus struct {
+	Generation         int64
+	ObservedGeneration int64
+	UpdatedReplicas    int32
+	CurrentRevision    string
+	UpdateRevision     string
+	UpdateStrategy     string
+	Conditions         []WorkloadCondition
+	Images             []ContainerImage
+}
+
+type WorkloadCondition struct {
+	Type    string
+	Status  corev1.ConditionStatus
+	Reason  string
+	Message string
+}
+
+type ContainerImage struct {
+	Container  string
+	Image      string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// revisionIndex ReplicaSets and ControllerRevisions by controller uid, and the
+// controller-revision-hash of running pods by controller uid, a nil index means revisions unknown.
+type revisionIndex struct {
+	replicaSets         map[types.UID][]appsv1.ReplicaSet
+	controllerRevisions map[types.UID][]appsv1.ControllerRevision
+	podHashes           map[types.UID]map[string]struct{}
+}
+
+// buildRevisionIndex returns nil when replicasets or controllerrevisions can't be listed.
+func buildRevisionIndex(cli api.MingleProxyClient, pods *corev1.PodList) *revisionIndex {
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), replicaSets); err != nil {
+		klog.Warningf("failed to list replicasets: %v", err)
+		return nil
+	}
+	controllerRevisions := &appsv1.ControllerRevisionList{}
+	if err := cli.GetRuntimeClient().List(context.TODO(), controllerRevisions); err != nil {
+		klog.Warningf("failed to list controllerrevisions: %v", err)
+		return nil
+	}
+	return newRevisionIndex(replicaSets, controllerRevisions, pods)
+}
+
+func newRevisionIndex(replicaSets *appsv1.ReplicaSetList, controllerRevisions *appsv1.ControllerRevisionList, pods *corev1.PodList) *revisionIndex {
+	idx := &revisionIndex{
+		replicaSets:         map[types.UID][]appsv1.ReplicaSet{},
+		controllerRevisions: map[types.UID][]appsv1.ControllerRevision{},
+		podHashes:           map[types.UID]map[string]struct{}{},
+	}
+	for _, rs := range replicaSets.Items {
+		if ref := metav1.GetControllerOf(&rs); ref != nil {
+			idx.replicaSets[ref.UID] = append(idx.replicaSets[ref.UID], rs)
+		}
+	}
+	for _, cr := range controllerRevisions.Items {
+		if ref := metav1.GetControllerOf(&cr); ref != nil {
+			idx.controllerRevisions[ref.UID] = append(idx.controllerRevisions[ref.UID], cr)
+		}
+	}
+	if pods == nil {
+		return idx
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		ref := metav1.GetControllerOf(pod)
+		hash, ok := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+		if ref == nil || !ok || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if _, ok = idx.podHashes[ref.UID]; !ok {
+			idx.podHashes[ref.UID] = map[string]struct{}{}
+		}
+		idx.podHashes[ref.UID][hash] = struct{}{}
+	}
+	return idx
+}
+
+// deploymentRevisions update revision is the newest owned ReplicaSet, current revision is
+// the oldest owned ReplicaSet still running replicas, equal to update revision once rollout finished.
+func (idx *revisionIndex) deploymentRevisions(deploy *appsv1.Deployment) (current, update string) {
+	if idx == nil {
+		return "", ""
+	}
+	var oldest, newest int64 = -1, -1
+	for _, rs := range idx.replicaSets[deploy.UID] {
+		revision, err := strconv.ParseInt(rs.Annotations[deploymentRevisionAnnotation], 10, 64)
+		if err != nil {
+			continue
+		}
+		if revision > newest {
+			newest = revision
+		}
+		if rs.Status.Replicas > 0 && (oldest < 0 || revision < oldest) {
+			oldest = revision
+		}
+	}
+	if newest < 0 {
+		return "", ""
+	}
+	if oldest < 0 {
+		oldest = newest
+	}
+	return strconv.FormatInt(oldest, 10), strconv.FormatInt(newest, 10)
+}
+
+// daemonsetRevisions update revision is the newest owned ControllerRevision, current revision is
+// the oldest owned ControllerRevision whose hash still labels a running pod.
+func (idx *revisionIndex) daemonsetRevisions(daemonset *appsv1.DaemonSet) (current, update string) {
+	if idx == nil {
+		return "", ""
+	}
+	var oldest, newest int64 = -1, -1
+	hashes := idx.podHashes[daemonset.UID]
+	for _, cr := range idx.controllerRevisions[daemonset.UID] {
+		if cr.Revision > newest {
+			newest = cr.Revision
+		}
+		if _, ok := hashes[cr.Labels[appsv1.ControllerRevisionHashLabelKey]]; ok && (oldest < 0 || cr.Revision < oldest) {
+			oldest = cr.Revision
+		}
+	}
+	if newest < 0 {
+		return "", ""
+	}
+	if oldest < 0 {
+		oldest = newest
+	}
+	return strconv.FormatInt(oldest, 10), strconv.FormatInt(newest, 10)
+}
+
+func buildDeploymentRollout(deploy *appsv1.Deployment, revisions *revisionIndex) RolloutStatus {
+	current, update := revisions.deploymentRevisions(deploy)
+	rs := RolloutStatus{
+		Generation:         deploy.Generation,
+		ObservedGeneration: deploy.Status.ObservedGeneration,
+		UpdatedReplicas:    deploy.Status.UpdatedReplicas,
+		CurrentRevision:    current,
+		UpdateRevision:     update,
+		UpdateStrategy:     string(deploy.Spec.Strategy.Type),
+		Conditions:         make([]WorkloadCondition, 0, len(deploy.Status.Conditions)),
+		Images:             buildContainerImages(deploy.Spec.Template.Spec.Containers),
+	}
+	for _, c := range deploy.Status.Conditions {
+		rs.Conditions = append(rs.Conditions, WorkloadCondition{Type: string(c.Type), Status: c.Status, Reason: c.Reason, Message: c.Message})
+	}
+	return rs
+}
+
+func buildStatefulsetRollout(statefulset *appsv1.StatefulSet) RolloutStatus {
+	rs := RolloutStatus{
+		Generation:         statefulset.Generation,
+		ObservedGeneration: statefulset.Status.ObservedGeneration,
+		UpdatedReplicas:    statefulset.Status.UpdatedReplicas,
+		CurrentRevision:    statefulset.Status.CurrentRevision,
+		UpdateRevision:     statefulset.Status.UpdateRevision,
+		UpdateStrategy:     string(statefulset.Spec.UpdateStrategy.Type),
+		Conditions:         make([]WorkloadCondition, 0, len(statefulset.Status.Conditions)),
+		Images:             buildContainerImages(statefulset.Spec.Template.Spec.Containers),
+	}
+	for _, c := range statefulset.Status.Conditions {
+		rs.Conditions = append(rs.Conditions, WorkloadCondition{Type: string(c.Type), Status: c.Status, Reason: c.Reason, Message: c.Message})
+	}
+	return rs
+}
+
+func buildDaemonsetRollout(daemonset *appsv1.DaemonSet, revisions *revisionIndex) RolloutStatus {
+	current, update := revisions.daemonsetRevisions(daemonset)
+	rs := RolloutStatus{
+		Generation:         daemonset.Generation,
+		ObservedGeneration: daemonset.Status.ObservedGeneration,
+		UpdatedReplicas:    daemonset.Status.UpdatedNumberScheduled,
+		CurrentRevision:    current,
+		UpdateRevision:     update,
+		UpdateStrategy:     string(daemonset.Spec.UpdateStrategy.Type),
+		Conditions:         make([]WorkloadCondition, 0, len(daemonset.Status.Conditions)),
+		Images:             buildContainerImages(daemonset.Spec.Template.Spec.Containers),
+	}
+	for _, c := range daemonset.Status.Conditions {
+		rs.Conditions = append(rs.Conditions, WorkloadCondition{Type: string(c.Type), Status: c.Status, Reason: c.Reason, Message: c.Message})
+	}
+	return rs
+}
+
+func buildContainerImages(list []corev1.Container) []ContainerImage {
+	images := make([]ContainerImage, 0, len(list))
+	for _, container := range list {
+		repository, tag, digest := ParseImageReference(container.Image)
+		images = append(images, ContainerImage{
+			Container:  container.Name,
+			Image:      container.Image,
+			Repository: repository,
+			Tag:        tag,
+			Digest:     digest,
+		})
+	}
+	return images
+}
+
+// ParseImageReference split image reference such as registry:5000/ns/name:tag@sha256:xxx,
+// tag is empty when image is untagged.
+func ParseImageReference(image string) (repository, tag, digest string) {
+	repository = image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		digest = repository[i+1:]
+		repository = repository[:i]
+	}
+	// the colon after last slash is tag separator, otherwise it's registry port
+	if i := strings.LastIndex(repository, ":"); i >= 0 && i > strings.LastIndex(repository, "/") {
+		tag = repository[i+1:]
+		repository = repository[:i]
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package resource
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRevisionIndex(t *testing.T) {
+	controller := true
+	ownedBy := func(uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{UID: uid, Controller: &controller}}
+	}
+	replicaSet := func(owner types.UID, revision string, replicas int32) appsv1.ReplicaSet {
+		return appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: ownedBy(owner),
+				Annotations:     map[string]string{deploymentRevisionAnnotation: revision},
+			},
+			Status: appsv1.ReplicaSetStatus{Replicas: replicas},
+		}
+	}
+	controllerRevision := func(owner types.UID, hash string, revision int64) appsv1.ControllerRevision {
+		return appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: ownedBy(owner),
+				Labels:          map[string]string{appsv1.ControllerRevisionHashLabelKey: hash},
+			},
+			Revision: revision,
+		}
+	}
+	pod := func(owner types.UID, hash string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: ownedBy(owner),
+				Labels:          map[string]string{appsv1.ControllerRevisionHashLabelKey: hash},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	idx := newRevisionIndex(
+		&appsv1.ReplicaSetList{Items: []appsv1.ReplicaSet{
+			replicaSet("rolling", "3", 2),
+			replicaSet("rolling", "4", 1),
+			replicaSet("rolling", "2", 0),
+			replicaSet("done", "5", 3),
+			replicaSet("done", "4", 0),
+			replicaSet("scaled-down", "7", 0),
+		}},
+		&appsv1.ControllerRevisionList{Items: []appsv1.ControllerRevision{
+			controllerRevision("ds-rolling", "aaa", 1),
+			controllerRevision("ds-rolling", "bbb", 2),
+			controllerRevision("ds-rolling", "ccc", 3),
+			controllerRevision("ds-done", "ddd", 1),
+			controllerRevision("ds-done", "eee", 2),
+		}},
+		&corev1.PodList{Items: []corev1.Pod{
+			pod("ds-rolling", "bbb", corev1.PodRunning),
+			pod("ds-rolling", "ccc", corev1.PodRunning),
+			pod("ds-rolling", "aaa", corev1.PodSucceeded),
+			pod("ds-done", "eee", corev1.PodRunning),
+		}},
+	)
+
+	deployments := []struct {
+		uid     types.UID
+		current string
+		update  string
+	}{
+		{"rolling", "3", "4"},
+		{"done", "5", "5"},
+		{"scaled-down", "7", "7"},
+		{"unknown", "", ""},
+	}
+	for _, tt := range deployments {
+		current, update := idx.deploymentRevisions(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{UID: tt.uid}})
+		if current != tt.current || update != tt.update {
+			t.Errorf("deployment %s: revisions = %s/%s, want %s/%s", tt.uid, current, update, tt.current, tt.update)
+		}
+	}
+
+	daemonsets := []struct {
+		uid     types.UID
+		current string
+		update  string
+	}{
+		{"ds-rolling", "2", "3"},
+		{"ds-done", "2", "2"},
+		{"unknown", "", ""},
+	}
+	for _, tt := range daemonsets {
+		current, update := idx.daemonsetRevisions(&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{UID: tt.uid}})
+		if current != tt.current || update != tt.update {
+			t.Errorf("daemonset %s: revisions = %s/%s, want %s/%s", tt.uid, current, update, tt.current, tt.update)
+		}
+	}
+
+	var missing *revisionIndex
+	if current, update := missing.deploymentRevisions(&appsv1.Deployment{}); current != "" || update != "" {
+		t.Errorf("nil index revisions = %s/%s, want empty", current, update)
+	}
+}
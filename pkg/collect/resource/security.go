@@ -0,0 +1,422 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/symcn/api"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Pod Security Standards levels, from the most permissive
+const (
+	PSSLevelPrivileged = "privileged"
+	PSSLevelBaseline   = "baseline"
+	PSSLevelRestricted = "restricted"
+)
+
+var (
+	// SecurityTargetLevel workloads meet this level are compliant
+	SecurityTargetLevel = PSSLevelBaseline
+
+	// baselineCapabilities capabilities allowed to add by baseline
+	baselineCapabilities = map[corev1.Capability]bool{
+		"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true,
+		"KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true,
+		"SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+	}
+
+	// baselineSysctls sysctls allowed to set by baseline
+	baselineSysctls = map[string]bool{
+		"kernel.shm_rmid_forced": true, "net.ipv4.ip_local_port_range": true, "net.ipv4.ip_unprivileged_port_start": true,
+		"net.ipv4.tcp_syncookies": true, "net.ipv4.ping_group_range": true,
+	}
+
+	securityLock              sync.Mutex
+	localCacheSecurityPosture = map[string]ClusterSecurityPosture{}
+)
+
+// podTemplateKinds workloads whose pod template is evaluated, pods created by
+// these workloads are skipped so every template is evaluated once.
+var podTemplateKinds = []workloadKind{
+	{
+		kind:     "Deployment",
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &appsv1.DeploymentList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			deploy, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				return nil, false
+			}
+			return podTemplate{name: deploy.Name, spec: &deploy.Spec.Template.Spec}, true
+		},
+	},
+	{
+		kind:     "StatefulSet",
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &appsv1.StatefulSetList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			statefulset, ok := obj.(*appsv1.StatefulSet)
+			if !ok {
+				return nil, false
+			}
+			return podTemplate{name: statefulset.Name, spec: &statefulset.Spec.Template.Spec}, true
+		},
+	},
+	{
+		kind:     "DaemonSet",
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &appsv1.DaemonSetList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			daemonset, ok := obj.(*appsv1.DaemonSet)
+			if !ok {
+				return nil, false
+			}
+			return podTemplate{name: daemonset.Name, spec: &daemonset.Spec.Template.Spec}, true
+		},
+	},
+	{
+		kind:     KindJob,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &batchv1.JobList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			job, ok := obj.(*batchv1.Job)
+			if !ok || metav1.GetControllerOf(job) != nil {
+				return nil, false
+			}
+			return podTemplate{name: job.Name, spec: &job.Spec.Template.Spec}, true
+		},
+	},
+	{
+		kind: KindCronJob,
+		newLists: []func() client.ObjectList{
+			func() client.ObjectList { return &batchv1.CronJobList{} },
+			func() client.ObjectList { return &batchv1beta1.CronJobList{} },
+		},
+		build: func(obj client.Object) (interface{}, bool) {
+			switch cronjob := obj.(type) {
+			case *batchv1.CronJob:
+				return podTemplate{name: cronjob.Name, spec: &cronjob.Spec.JobTemplate.Spec.Template.Spec}, true
+			case *batchv1beta1.CronJob:
+				return podTemplate{name: cronjob.Name, spec: &cronjob.Spec.JobTemplate.Spec.Template.Spec}, true
+			}
+			return nil, false
+		},
+	},
+	{
+		kind:     KindReplicaSet,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &appsv1.ReplicaSetList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			rs, ok := obj.(*appsv1.ReplicaSet)
+			if !ok || metav1.GetControllerOf(rs) != nil {
+				return nil, false
+			}
+			return podTemplate{name: rs.Name, spec: &rs.Spec.Template.Spec}, true
+		},
+	},
+	{
+		kind:     KindPod,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &corev1.PodList{} }},
+		build: func(obj client.Object) (interface{}, bool) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || len(pod.OwnerReferences) > 0 {
+				return nil, false
+			}
+			return podTemplate{name: pod.Name, spec: &pod.Spec}, true
+		},
+	},
+}
+
+type podTemplate struct {
+	name string
+	spec *corev1.PodSpec
+}
+
+type ClusterSecurityPosture struct {
+	ClusterName string
+	TargetLevel string
+	// Score percentage of workloads meet TargetLevel
+	Score      float64
+	Workloads  int
+	LevelCount map[string]int
+	Namespaces []NamespaceSecurityPosture
+}
+
+type NamespaceSecurityPosture struct {
+	Name       string
+	Score      float64
+	Workloads  int
+	LevelCount map[string]int
+	// Violations only workloads have violations or findings are listed
+	Violations []WorkloadSecurityPosture
+}
+
+type WorkloadSecurityPosture struct {
+	Kind string
+	Name string
+	// Level the most restrictive level the pod template meets
+	Level      string
+	Violations []SecurityViolation
+	// Findings best practices not part of Pod Security Standards, don't affect Level
+	Findings []SecurityViolation
+}
+
+type SecurityViolation struct {
+	Check string
+	// Level the level requires this check
+	Level     string
+	Container string
+	Message   string
+}
+
+// CollectSecurityPosture evaluate pod templates of workloads against Pod Security Standards,
+// missing resource limits is reported as a finding out of the standards.
+func CollectSecurityPosture(cli api.MingleProxyClient, pods *corev1.PodList) {
+	posture := ClusterSecurityPosture{
+		ClusterName: cli.GetClusterCfgInfo().GetName(),
+		TargetLevel: SecurityTargetLevel,
+		LevelCount:  newLevelCount(),
+		Namespaces:  []NamespaceSecurityPosture{},
+	}
+
+	namespaces := map[string]*NamespaceSecurityPosture{}
+	for _, wk := range podTemplateKinds {
+		statistics, err := collectWorkloadKind(cli, wk, pods)
+		if err != nil {
+			klog.Warning(err)
+			continue
+		}
+		for namespace, list := range statistics.List {
+			nsp, ok := namespaces[namespace]
+			if !ok {
+				nsp = &NamespaceSecurityPosture{
+					Name:       namespace,
+					LevelCount: newLevelCount(),
+					Violations: []WorkloadSecurityPosture{},
+				}
+				namespaces[namespace] = nsp
+			}
+			for _, item := range list {
+				pt, ok := item.(podTemplate)
+				if !ok {
+					continue
+				}
+				wsp := evaluatePodSpec(pt.spec)
+				wsp.Kind, wsp.Name = wk.kind, pt.name
+				nsp.Workloads++
+				nsp.LevelCount[wsp.Level]++
+				if len(wsp.Violations) > 0 || len(wsp.Findings) > 0 {
+					nsp.Violations = append(nsp.Violations, wsp)
+				}
+			}
+		}
+	}
+
+	for _, nsp := range namespaces {
+		nsp.Score = complianceScore(nsp.LevelCount, nsp.Workloads)
+		sort.Slice(nsp.Violations, func(i, j int) bool {
+			return nsp.Violations[i].Kind+"/"+nsp.Violations[i].Name < nsp.Violations[j].Kind+"/"+nsp.Violations[j].Name
+		})
+		posture.Workloads += nsp.Workloads
+		for level, n := range nsp.LevelCount {
+			posture.LevelCount[level] += n
+		}
+		posture.Namespaces = append(posture.Namespaces, *nsp)
+	}
+	posture.Score = complianceScore(posture.LevelCount, posture.Workloads)
+	sort.Slice(posture.Namespaces, func(i, j int) bool {
+		return posture.Namespaces[i].Name < posture.Namespaces[j].Name
+	})
+	putCacheSecurityPosture(posture.ClusterName, posture)
+
+	data, _ := json.Marshal(posture)
+	klog.V(4).Infof("get security posture:\n%s", string(data))
+}
+
+func evaluatePodSpec(spec *corev1.PodSpec) WorkloadSecurityPosture {
+	wsp := WorkloadSecurityPosture{Violations: []SecurityViolation{}, Findings: []SecurityViolation{}}
+	add := func(check, level, container, message string) {
+		wsp.Violations = append(wsp.Violations, SecurityViolation{Check: check, Level: level, Container: container, Message: message})
+	}
+
+	// baseline
+	if spec.HostNetwork {
+		add("hostNetwork", PSSLevelBaseline, "", "hostNetwork is true")
+	}
+	if spec.HostPID {
+		add("hostPID", PSSLevelBaseline, "", "hostPID is true")
+	}
+	if spec.HostIPC {
+		add("hostIPC", PSSLevelBaseline, "", "hostIPC is true")
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			add("hostPath", PSSLevelBaseline, "", fmt.Sprintf("volume %s mounts host path %s", v.Name, v.HostPath.Path))
+		} else if !isRestrictedVolume(&v.VolumeSource) {
+			add("volumeTypes", PSSLevelRestricted, "", fmt.Sprintf("volume %s type is not allowed", v.Name))
+		}
+	}
+
+	var podRunAsNonRoot, podSeccomp bool
+	if psc := spec.SecurityContext; psc != nil {
+		podRunAsNonRoot = psc.RunAsNonRoot != nil && *psc.RunAsNonRoot
+		podSeccomp = psc.SeccompProfile != nil && psc.SeccompProfile.Type != corev1.SeccompProfileTypeUnconfined
+		for _, sysctl := range psc.Sysctls {
+			if !baselineSysctls[sysctl.Name] {
+				add("sysctls", PSSLevelBaseline, "", fmt.Sprintf("sysctl %s is set", sysctl.Name))
+			}
+		}
+		if psc.RunAsUser != nil && *psc.RunAsUser == 0 {
+			add("runAsUser", PSSLevelRestricted, "", "runAsUser is 0")
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, ec := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(ec.EphemeralContainerCommon))
+	}
+	for i, c := range containers {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			add("privileged", PSSLevelBaseline, c.Name, "container is privileged")
+		}
+		for _, p := range c.Ports {
+			if p.HostPort != 0 {
+				add("hostPorts", PSSLevelBaseline, c.Name, fmt.Sprintf("hostPort %d is used", p.HostPort))
+			}
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			add("procMount", PSSLevelBaseline, c.Name, fmt.Sprintf("procMount is %s", *sc.ProcMount))
+		}
+		var added []corev1.Capability
+		dropAll := false
+		if sc.Capabilities != nil {
+			added = sc.Capabilities.Add
+			for _, capability := range sc.Capabilities.Drop {
+				dropAll = dropAll || capability == "ALL"
+			}
+		}
+		for _, capability := range added {
+			if !baselineCapabilities[capability] {
+				add("capabilities", PSSLevelBaseline, c.Name, fmt.Sprintf("capability %s is added", capability))
+			} else if capability != "NET_BIND_SERVICE" {
+				add("capabilities", PSSLevelRestricted, c.Name, fmt.Sprintf("capability %s is added", capability))
+			}
+		}
+
+		// restricted
+		if !dropAll {
+			add("capabilities", PSSLevelRestricted, c.Name, "capabilities ALL is not dropped")
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			add("allowPrivilegeEscalation", PSSLevelRestricted, c.Name, "allowPrivilegeEscalation is not false")
+		}
+		runAsNonRoot := podRunAsNonRoot
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = *sc.RunAsNonRoot
+		}
+		if !runAsNonRoot {
+			add("runAsNonRoot", PSSLevelRestricted, c.Name, "runAsNonRoot is not true")
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			add("runAsUser", PSSLevelRestricted, c.Name, "runAsUser is 0")
+		}
+		seccomp := podSeccomp
+		if sc.SeccompProfile != nil {
+			seccomp = sc.SeccompProfile.Type != corev1.SeccompProfileTypeUnconfined
+		}
+		if !seccomp {
+			add("seccompProfile", PSSLevelRestricted, c.Name, "seccompProfile is not RuntimeDefault or Localhost")
+		}
+
+		// not part of Pod Security Standards, ephemeral containers can't set resources
+		ephemeral := i >= len(spec.InitContainers)+len(spec.Containers)
+		if !ephemeral && (c.Resources.Limits.Cpu().IsZero() || c.Resources.Limits.Memory().IsZero()) {
+			wsp.Findings = append(wsp.Findings, SecurityViolation{Check: "resourceLimits", Container: c.Name, Message: "cpu or memory limit is not set"})
+		}
+	}
+
+	wsp.Level = PSSLevelRestricted
+	for _, v := range wsp.Violations {
+		if v.Level == PSSLevelBaseline {
+			wsp.Level = PSSLevelPrivileged
+			break
+		}
+		wsp.Level = PSSLevelBaseline
+	}
+	return wsp
+}
+
+// isRestrictedVolume volume types allowed by restricted
+func isRestrictedVolume(vs *corev1.VolumeSource) bool {
+	return vs.ConfigMap != nil || vs.CSI != nil || vs.DownwardAPI != nil || vs.EmptyDir != nil ||
+		vs.Ephemeral != nil || vs.PersistentVolumeClaim != nil || vs.Projected != nil || vs.Secret != nil
+}
+
+func newLevelCount() map[string]int {
+	return map[string]int{PSSLevelPrivileged: 0, PSSLevelBaseline: 0, PSSLevelRestricted: 0}
+}
+
+// complianceScore percentage of workloads meet SecurityTargetLevel, empty is fully compliant
+func complianceScore(levelCount map[string]int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	compliant := levelCount[PSSLevelRestricted]
+	switch SecurityTargetLevel {
+	case PSSLevelBaseline:
+		compliant += levelCount[PSSLevelBaseline]
+	case PSSLevelPrivileged:
+		compliant = total
+	}
+	return float64(compliant) * 100 / float64(total)
+}
+
+func putCacheSecurityPosture(clusterName string, posture ClusterSecurityPosture) {
+	securityLock.Lock()
+	defer securityLock.Unlock()
+
+	if len(localCacheSecurityPosture) == 0 {
+		localCacheSecurityPosture = map[string]ClusterSecurityPosture{}
+	}
+	localCacheSecurityPosture[clusterName] = posture
+}
+
+func deleteCacheSecurityPosture(clusterName string) {
+	securityLock.Lock()
+	defer securityLock.Unlock()
+
+	delete(localCacheSecurityPosture, clusterName)
+}
+
+func GetCacheSecurityPostureWithClusterName(clusterName string) (ClusterSecurityPosture, bool) {
+	securityLock.Lock()
+	defer securityLock.Unlock()
+
+	if len(localCacheSecurityPosture) == 0 {
+		return ClusterSecurityPosture{}, false
+	}
+	posture, ok := localCacheSecurityPosture[clusterName]
+	return posture, ok
+}
+
+func GetAllCacheSecurityPosture() []ClusterSecurityPosture {
+	securityLock.Lock()
+	defer securityLock.Unlock()
+
+	list := make([]ClusterSecurityPosture, 0, len(localCacheSecurityPosture))
+	for _, posture := range localCacheSecurityPosture {
+		list = append(list, posture)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterName < list[j].ClusterName
+	})
+	return list
+}
@@ -0,0 +1,111 @@
+package resource
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestEvaluatePodSpec(t *testing.T) {
+	yes, no := true, false
+	root, nonRoot := int64(0), int64(1000)
+	unmasked := corev1.UnmaskedProcMount
+	restrictedContainer := func(name string) corev1.Container {
+		return corev1.Container{
+			Name: name,
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: &no,
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			},
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		}
+	}
+	restrictedSpec := func(mutate func(spec *corev1.PodSpec)) *corev1.PodSpec {
+		spec := &corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot:   &yes,
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []corev1.Container{restrictedContainer("app")},
+		}
+		if mutate != nil {
+			mutate(spec)
+		}
+		return spec
+	}
+
+	tests := []struct {
+		name     string
+		spec     *corev1.PodSpec
+		level    string
+		check    string
+		findings int
+	}{
+		{"restricted", restrictedSpec(nil), PSSLevelRestricted, "", 0},
+		{"missing limits is finding only", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Containers[0].Resources = corev1.ResourceRequirements{}
+		}), PSSLevelRestricted, "", 1},
+		{"host path", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Volumes = []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var"}}}}
+		}), PSSLevelPrivileged, "hostPath", 0},
+		{"allowed volume", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Volumes = []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+		}), PSSLevelRestricted, "", 0},
+		{"volume type", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Volumes = []corev1.Volume{{Name: "nfs", VolumeSource: corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/"}}}}
+		}), PSSLevelBaseline, "volumeTypes", 0},
+		{"pod run as root user", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.SecurityContext.RunAsUser = &root
+		}), PSSLevelBaseline, "runAsUser", 0},
+		{"container run as root user", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.RunAsUser = &root
+		}), PSSLevelBaseline, "runAsUser", 0},
+		{"run as non root user", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.SecurityContext.RunAsUser = &nonRoot
+		}), PSSLevelRestricted, "", 0},
+		{"safe sysctl", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "net.ipv4.tcp_syncookies", Value: "1"}}
+		}), PSSLevelRestricted, "", 0},
+		{"unsafe sysctl", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "65536"}}
+		}), PSSLevelPrivileged, "sysctls", 0},
+		{"proc mount", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.ProcMount = &unmasked
+		}), PSSLevelPrivileged, "procMount", 0},
+		{"privileged ephemeral container", restrictedSpec(func(spec *corev1.PodSpec) {
+			debug := restrictedContainer("debug")
+			debug.SecurityContext.Privileged = &yes
+			debug.Resources = corev1.ResourceRequirements{}
+			spec.EphemeralContainers = []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon(debug)}}
+		}), PSSLevelPrivileged, "privileged", 0},
+		{"capabilities not dropped", restrictedSpec(func(spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.Capabilities = nil
+		}), PSSLevelBaseline, "capabilities", 0},
+	}
+	for _, tt := range tests {
+		wsp := evaluatePodSpec(tt.spec)
+		if wsp.Level != tt.level {
+			t.Errorf("%s: level = %s, want %s, violations %+v", tt.name, wsp.Level, tt.level, wsp.Violations)
+		}
+		if len(wsp.Findings) != tt.findings {
+			t.Errorf("%s: findings = %+v, want %d", tt.name, wsp.Findings, tt.findings)
+		}
+		if tt.check == "" {
+			if len(wsp.Violations) != 0 {
+				t.Errorf("%s: unexpected violations %+v", tt.name, wsp.Violations)
+			}
+			continue
+		}
+		found := false
+		for _, v := range wsp.Violations {
+			found = found || v.Check == tt.check
+		}
+		if !found {
+			t.Errorf("%s: expected %s violation, got %+v", tt.name, tt.check, wsp.Violations)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package resource
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type ClusterUtilization struct {
+	ResourceUtilization
+	Nodes map[string]ResourceUtilization
+}
+
+type ResourceUtilization struct {
+	Allocatable  corev1.ResourceList
+	Requests     corev1.ResourceList
+	Limits       corev1.ResourceList
+	Headroom     corev1.ResourceList
+	RequestRatio map[corev1.ResourceName]float64
+	LimitRatio   map[corev1.ResourceName]float64
+}
+
+// buildClusterUtilization sum requests and limits of all scheduled and non-terminal pods
+// per node and per cluster, then compute allocation ratio and headroom against allocatable.
+func buildClusterUtilization(nodes *corev1.NodeList, pods *corev1.PodList) ClusterUtilization {
+	cluster := newResourceUtilization()
+	nodeUtilization := map[string]ResourceUtilization{}
+	for _, node := range nodes.Items {
+		nu := newResourceUtilization()
+		AddResourceList(nu.Allocatable, node.Status.Allocatable)
+		AddResourceList(cluster.Allocatable, node.Status.Allocatable)
+		nodeUtilization[node.Name] = nu
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isPodScheduledAndActive(pod) {
+			continue
+		}
+		requests, limits := podRequestsAndLimits(pod)
+		requests[corev1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+
+		AddResourceList(cluster.Requests, requests)
+		AddResourceList(cluster.Limits, limits)
+		if nu, ok := nodeUtilization[pod.Spec.NodeName]; ok {
+			AddResourceList(nu.Requests, requests)
+			AddResourceList(nu.Limits, limits)
+		}
+	}
+
+	for name, nu := range nodeUtilization {
+		nu.complete()
+		nodeUtilization[name] = nu
+	}
+	cluster.complete()
+
+	return ClusterUtilization{
+		ResourceUtilization: cluster,
+		Nodes:               nodeUtilization,
+	}
+}
+
+func newResourceUtilization() ResourceUtilization {
+	return ResourceUtilization{
+		Allocatable:  corev1.ResourceList{},
+		Requests:     corev1.ResourceList{},
+		Limits:       corev1.ResourceList{},
+		Headroom:     corev1.ResourceList{},
+		RequestRatio: map[corev1.ResourceName]float64{},
+		LimitRatio:   map[corev1.ResourceName]float64{},
+	}
+}
+
+// complete compute headroom and ratio, headroom is negative when overcommitted.
+func (ru *ResourceUtilization) complete() {
+	for name, allocatable := range ru.Allocatable {
+		headroom := allocatable.DeepCopy()
+		if requests, ok := ru.Requests[name]; ok {
+			headroom.Sub(requests)
+		}
+		ru.Headroom[name] = headroom
+
+		total := allocatable.AsApproximateFloat64()
+		if total <= 0 {
+			continue
+		}
+		requests := ru.Requests[name]
+		limits := ru.Limits[name]
+		ru.RequestRatio[name] = requests.AsApproximateFloat64() / total
+		ru.LimitRatio[name] = limits.AsApproximateFloat64() / total
+	}
+}
+
+// podRequestsAndLimits return effective pod requests and limits the way scheduler does:
+// max(sum of containers, max of any init container) plus pod overhead.
+func podRequestsAndLimits(pod *corev1.Pod) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		AddResourceList(requests, container.Resources.Requests)
+		AddResourceList(limits, container.Resources.Limits)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(requests, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+
+	if pod.Spec.Overhead != nil {
+		AddResourceList(requests, pod.Spec.Overhead)
+		// limits only count overhead for resource has limit
+		for name, value := range pod.Spec.Overhead {
+			if q, ok := limits[name]; ok {
+				q.Add(value)
+				limits[name] = q
+			}
+		}
+	}
+	return
+}
+
+// maxResourceList set dst to the greater quantity of dst and src for each resource name
+func maxResourceList(dst, src corev1.ResourceList) {
+	for name, value := range src {
+		if q, ok := dst[name]; !ok || value.Cmp(q) > 0 {
+			dst[name] = value.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package resource
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodRequestsAndLimits(t *testing.T) {
+	container := func(requests, limits corev1.ResourceList) corev1.Container {
+		return corev1.Container{Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits}}
+	}
+
+	tests := []struct {
+		name     string
+		spec     corev1.PodSpec
+		requests map[corev1.ResourceName]string
+		limits   map[corev1.ResourceName]string
+	}{
+		{
+			name: "sum of containers",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				container(resourceList("cpu", "100m", "memory", "128Mi"), resourceList("cpu", "200m")),
+				container(resourceList("cpu", "300m", "memory", "256Mi"), resourceList("cpu", "500m", "memory", "512Mi")),
+			}},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "400m", corev1.ResourceMemory: "384Mi"},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "700m", corev1.ResourceMemory: "512Mi"},
+		},
+		{
+			name: "init container larger than containers",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					container(resourceList("cpu", "1", "memory", "64Mi"), resourceList("cpu", "2")),
+					container(resourceList("cpu", "500m"), nil),
+				},
+				Containers: []corev1.Container{
+					container(resourceList("cpu", "200m", "memory", "128Mi"), resourceList("cpu", "1")),
+				},
+			},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "1", corev1.ResourceMemory: "128Mi"},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "2"},
+		},
+		{
+			name: "overhead",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					container(resourceList("cpu", "100m", "memory", "64Mi", string(resourceGPU), "1"), resourceList("cpu", "100m", string(resourceGPU), "1")),
+				},
+				Overhead: resourceList("cpu", "250m", "memory", "120Mi"),
+			},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "350m", corev1.ResourceMemory: "184Mi", resourceGPU: "1"},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "350m", resourceGPU: "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, limits := podRequestsAndLimits(&corev1.Pod{Spec: tt.spec})
+			expectQuantities(t, "requests", requests, tt.requests)
+			expectQuantities(t, "limits", limits, tt.limits)
+			if len(limits) != len(tt.limits) {
+				t.Errorf("unexpected limits %v", limits)
+			}
+		})
+	}
+}
+
+func TestBuildClusterUtilization(t *testing.T) {
+	pod := func(nodeName string, phase corev1.PodPhase, requests corev1.ResourceList) corev1.Pod {
+		return corev1.Pod{
+			Spec: corev1.PodSpec{
+				NodeName:   nodeName,
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: requests, Limits: requests}}},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	pods := &corev1.PodList{Items: []corev1.Pod{
+		pod("cpu-node", corev1.PodRunning, resourceList("cpu", "6", "memory", "10Gi")),
+		pod("cpu-node", corev1.PodRunning, resourceList("cpu", "3", "memory", "5Gi")),
+		pod("gpu-node", corev1.PodRunning, resourceList("cpu", "2", "memory", "8Gi", string(resourceGPU), "2")),
+		// not counted
+		pod("gpu-node", corev1.PodSucceeded, resourceList("cpu", "4", string(resourceGPU), "2")),
+		pod("gpu-node", corev1.PodFailed, resourceList("cpu", "4")),
+		pod("", corev1.PodPending, resourceList("cpu", "4")),
+	}}
+
+	utilization := buildClusterUtilization(syntheticNodes(), pods)
+	expectQuantities(t, "cluster requests", utilization.Requests, map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    "11",
+		corev1.ResourceMemory: "23Gi",
+		corev1.ResourcePods:   "3",
+		resourceGPU:           "2",
+	})
+	expectQuantities(t, "cluster headroom", utilization.Headroom, map[corev1.ResourceName]string{
+		corev1.ResourceCPU: "12",
+		resourceGPU:        "2",
+	})
+	if ratio := utilization.RequestRatio[resourceGPU]; ratio != 0.5 {
+		t.Errorf("expected gpu request ratio 0.5, got %v", ratio)
+	}
+
+	// overcommitted node has negative headroom
+	cpuNode := utilization.Nodes["cpu-node"]
+	expectQuantities(t, "cpu-node headroom", cpuNode.Headroom, map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    "-1500m",
+		corev1.ResourceMemory: "15Gi",
+		corev1.ResourcePods:   "108",
+	})
+	if ratio := cpuNode.RequestRatio[corev1.ResourceCPU]; ratio != 1.2 {
+		t.Errorf("expected cpu-node cpu request ratio 1.2, got %v", ratio)
+	}
+	if _, ok := cpuNode.Requests[resourceGPU]; ok {
+		t.Errorf("cpu-node should not request gpu, got %v", cpuNode.Requests)
+	}
+}
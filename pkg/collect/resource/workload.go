@@ -0,0 +1,448 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/symcn/api"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	KindJob                     = "Job"
+	KindCronJob                 = "CronJob"
+	KindReplicaSet              = "ReplicaSet"
+	KindPod                     = "Pod"
+	KindHorizontalPodAutoscaler = "HorizontalPodAutoscaler"
+)
+
+// workloadKind describe how to list and build status for one workload kind,
+// add a new kind only need append it to workloadKinds.
+type workloadKind struct {
+	kind string
+	// newLists are tried in order, fallback to older api version when newer one is not served
+	newLists []func() client.ObjectList
+	// build returns false when the object should be skipped
+	build func(obj client.Object) (interface{}, bool)
+}
+
+var workloadKinds = []workloadKind{
+	{
+		kind:     KindJob,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &batchv1.JobList{} }},
+		build:    buildJobStatus,
+	},
+	{
+		kind: KindCronJob,
+		newLists: []func() client.ObjectList{
+			func() client.ObjectList { return &batchv1.CronJobList{} },
+			func() client.ObjectList { return &batchv1beta1.CronJobList{} },
+		},
+		build: buildCronJobStatus,
+	},
+	{
+		kind:     KindReplicaSet,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &appsv1.ReplicaSetList{} }},
+		build:    buildOrphanReplicaSetStatus,
+	},
+	{
+		kind:     KindPod,
+		newLists: []func() client.ObjectList{func() client.ObjectList { return &corev1.PodList{} }},
+		build:    buildUnownedPodStatus,
+	},
+	{
+		kind: KindHorizontalPodAutoscaler,
+		newLists: []func() client.ObjectList{
+			// autoscaling/v2 served since 1.23, v2beta2 removed in 1.26, v1 only has cpu target
+			func() client.ObjectList { return &autoscalingv2.HorizontalPodAutoscalerList{} },
+			func() client.ObjectList { return &autoscalingv2beta2.HorizontalPodAutoscalerList{} },
+			func() client.ObjectList { return &autoscalingv1.HorizontalPodAutoscalerList{} },
+		},
+		build: buildHPAStatus,
+	},
+}
+
+// WorkloadStatistics workload status group by namespace
+type WorkloadStatistics struct {
+	List map[string][]interface{}
+}
+
+type JobStatus struct {
+	Name           string
+	ShowName       string
+	Completions    *int32
+	Parallelism    *int32
+	Active         int32
+	Succeeded      int32
+	Failed         int32
+	StartTime      *metav1.Time
+	CompletionTime *metav1.Time
+	Resource       Resouces
+}
+
+type CronJobStatus struct {
+	Name             string
+	ShowName         string
+	Schedule         string
+	Suspend          bool
+	Active           int32
+	LastScheduleTime *metav1.Time
+	Resource         Resouces
+}
+
+type ReplicaSetStatus struct {
+	Name          string
+	ShowName      string
+	Replicas      int32
+	ReadyReplicas int32
+	Resource      Resouces
+}
+
+type PodStatus struct {
+	Name     string
+	ShowName string
+	Phase    corev1.PodPhase
+	NodeName string
+	Restarts int32
+	Resource Resouces
+}
+
+type HPAStatus struct {
+	Name            string
+	TargetKind      string
+	TargetName      string
+	TargetFound     bool
+	MinReplicas     int32
+	MaxReplicas     int32
+	CurrentReplicas int32
+	DesiredReplicas int32
+	TargetMetrics   []string
+	CurrentMetrics  []string
+}
+
+func collectWorkloadKinds(cli api.MingleProxyClient, pods *corev1.PodList) map[string]WorkloadStatistics {
+	result := make(map[string]WorkloadStatistics, len(workloadKinds))
+	for _, wk := range workloadKinds {
+		statistics, err := collectWorkloadKind(cli, wk, pods)
+		if err != nil {
+			klog.Warning(err)
+			continue
+		}
+		result[wk.kind] = statistics
+	}
+	return result
+}
+
+// collectWorkloadKind pods listed in this collect cycle are reused for Pod kind, nil lists again.
+func collectWorkloadKind(cli api.MingleProxyClient, wk workloadKind, pods *corev1.PodList) (WorkloadStatistics, error) {
+	statistics := WorkloadStatistics{List: map[string][]interface{}{}}
+
+	var list client.ObjectList
+	var err error
+	if wk.kind == KindPod && pods != nil {
+		list = pods
+	} else {
+		for _, newList := range wk.newLists {
+			list = newList()
+			if err = cli.GetRuntimeClient().List(context.TODO(), list); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return statistics, fmt.Errorf("get all %s failed: %+v", wk.kind, err)
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return statistics, fmt.Errorf("extract %s list failed: %+v", wk.kind, err)
+	}
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			continue
+		}
+		status, ok := wk.build(obj)
+		if !ok {
+			continue
+		}
+		statistics.List[obj.GetNamespace()] = append(statistics.List[obj.GetNamespace()], status)
+	}
+	return statistics, nil
+}
+
+func buildJobStatus(obj client.Object) (interface{}, bool) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		return nil, false
+	}
+	// job created by cronjob is counted in cronjob
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == KindCronJob {
+		return nil, false
+	}
+	return JobStatus{
+		Name:           job.Name,
+		ShowName:       getShowName(job.Labels),
+		Completions:    job.Spec.Completions,
+		Parallelism:    job.Spec.Parallelism,
+		Active:         job.Status.Active,
+		Succeeded:      job.Status.Succeeded,
+		Failed:         job.Status.Failed,
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+		Resource:       buildResource(job.Spec.Template.Spec.Containers),
+	}, true
+}
+
+func buildCronJobStatus(obj client.Object) (interface{}, bool) {
+	switch cronjob := obj.(type) {
+	case *batchv1.CronJob:
+		return CronJobStatus{
+			Name:             cronjob.Name,
+			ShowName:         getShowName(cronjob.Labels),
+			Schedule:         cronjob.Spec.Schedule,
+			Suspend:          cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend,
+			Active:           int32(len(cronjob.Status.Active)),
+			LastScheduleTime: cronjob.Status.LastScheduleTime,
+			Resource:         buildResource(cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers),
+		}, true
+	case *batchv1beta1.CronJob:
+		return CronJobStatus{
+			Name:             cronjob.Name,
+			ShowName:         getShowName(cronjob.Labels),
+			Schedule:         cronjob.Spec.Schedule,
+			Suspend:          cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend,
+			Active:           int32(len(cronjob.Status.Active)),
+			LastScheduleTime: cronjob.Status.LastScheduleTime,
+			Resource:         buildResource(cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers),
+		}, true
+	}
+	return nil, false
+}
+
+// buildOrphanReplicaSetStatus only build replicaset without controller,
+// replicaset managed by deployment is counted in deployment.
+func buildOrphanReplicaSetStatus(obj client.Object) (interface{}, bool) {
+	rs, ok := obj.(*appsv1.ReplicaSet)
+	if !ok || metav1.GetControllerOf(rs) != nil {
+		return nil, false
+	}
+	return ReplicaSetStatus{
+		Name:          rs.Name,
+		ShowName:      getShowName(rs.Labels),
+		Replicas:      rs.Status.Replicas,
+		ReadyReplicas: rs.Status.ReadyReplicas,
+		Resource:      buildResource(rs.Spec.Template.Spec.Containers),
+	}, true
+}
+
+// buildUnownedPodStatus only build bare pod without any owner.
+func buildUnownedPodStatus(obj client.Object) (interface{}, bool) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || len(pod.OwnerReferences) > 0 {
+		return nil, false
+	}
+	var restarts int32
+	for _, cs := range pod.Status.ContainerStatuses {
+		restarts += cs.RestartCount
+	}
+	return PodStatus{
+		Name:     pod.Name,
+		ShowName: getShowName(pod.Labels),
+		Phase:    pod.Status.Phase,
+		NodeName: pod.Spec.NodeName,
+		Restarts: restarts,
+		Resource: buildResource(pod.Spec.Containers),
+	}, true
+}
+
+func buildHPAStatus(obj client.Object) (interface{}, bool) {
+	switch hpa := obj.(type) {
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		return buildHPAV2Status(hpa), true
+	case *autoscalingv2beta2.HorizontalPodAutoscaler:
+		// v2beta2 has the same schema as v2
+		data, err := json.Marshal(hpa)
+		if err != nil {
+			return nil, false
+		}
+		v2 := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err = json.Unmarshal(data, v2); err != nil {
+			return nil, false
+		}
+		return buildHPAV2Status(v2), true
+	case *autoscalingv1.HorizontalPodAutoscaler:
+		status := HPAStatus{
+			Name:            hpa.Name,
+			TargetKind:      hpa.Spec.ScaleTargetRef.Kind,
+			TargetName:      hpa.Spec.ScaleTargetRef.Name,
+			MinReplicas:     derefInt32(hpa.Spec.MinReplicas, 1),
+			MaxReplicas:     hpa.Spec.MaxReplicas,
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+			TargetMetrics:   []string{},
+			CurrentMetrics:  []string{},
+		}
+		if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+			status.TargetMetrics = append(status.TargetMetrics, fmt.Sprintf("resource cpu %d%%", *hpa.Spec.TargetCPUUtilizationPercentage))
+		}
+		if hpa.Status.CurrentCPUUtilizationPercentage != nil {
+			status.CurrentMetrics = append(status.CurrentMetrics, fmt.Sprintf("resource cpu %d%%", *hpa.Status.CurrentCPUUtilizationPercentage))
+		}
+		return status, true
+	}
+	return nil, false
+}
+
+func buildHPAV2Status(hpa *autoscalingv2.HorizontalPodAutoscaler) HPAStatus {
+	status := HPAStatus{
+		Name:            hpa.Name,
+		TargetKind:      hpa.Spec.ScaleTargetRef.Kind,
+		TargetName:      hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:     derefInt32(hpa.Spec.MinReplicas, 1),
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		TargetMetrics:   []string{},
+		CurrentMetrics:  []string{},
+	}
+	for _, m := range hpa.Spec.Metrics {
+		status.TargetMetrics = append(status.TargetMetrics, describeMetricSpec(m))
+	}
+	for _, m := range hpa.Status.CurrentMetrics {
+		status.CurrentMetrics = append(status.CurrentMetrics, describeMetricStatus(m))
+	}
+	return status
+}
+
+func describeMetricSpec(m autoscalingv2.MetricSpec) string {
+	switch m.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if m.Resource != nil {
+			return fmt.Sprintf("resource %s %s", m.Resource.Name, describeMetricTarget(m.Resource.Target))
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if m.ContainerResource != nil {
+			return fmt.Sprintf("container %s resource %s %s", m.ContainerResource.Container, m.ContainerResource.Name, describeMetricTarget(m.ContainerResource.Target))
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if m.Pods != nil {
+			return fmt.Sprintf("pods %s %s", m.Pods.Metric.Name, describeMetricTarget(m.Pods.Target))
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if m.Object != nil {
+			return fmt.Sprintf("object %s/%s %s %s", m.Object.DescribedObject.Kind, m.Object.DescribedObject.Name, m.Object.Metric.Name, describeMetricTarget(m.Object.Target))
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if m.External != nil {
+			return fmt.Sprintf("external %s %s", m.External.Metric.Name, describeMetricTarget(m.External.Target))
+		}
+	}
+	return string(m.Type)
+}
+
+func describeMetricTarget(t autoscalingv2.MetricTarget) string {
+	switch {
+	case t.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *t.AverageUtilization)
+	case t.AverageValue != nil:
+		return fmt.Sprintf("average %s", t.AverageValue.String())
+	case t.Value != nil:
+		return t.Value.String()
+	}
+	return ""
+}
+
+func describeMetricStatus(m autoscalingv2.MetricStatus) string {
+	switch m.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if m.Resource != nil {
+			return fmt.Sprintf("resource %s %s", m.Resource.Name, describeMetricValue(m.Resource.Current))
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if m.ContainerResource != nil {
+			return fmt.Sprintf("container %s resource %s %s", m.ContainerResource.Container, m.ContainerResource.Name, describeMetricValue(m.ContainerResource.Current))
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if m.Pods != nil {
+			return fmt.Sprintf("pods %s %s", m.Pods.Metric.Name, describeMetricValue(m.Pods.Current))
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if m.Object != nil {
+			return fmt.Sprintf("object %s/%s %s %s", m.Object.DescribedObject.Kind, m.Object.DescribedObject.Name, m.Object.Metric.Name, describeMetricValue(m.Object.Current))
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if m.External != nil {
+			return fmt.Sprintf("external %s %s", m.External.Metric.Name, describeMetricValue(m.External.Current))
+		}
+	}
+	return string(m.Type)
+}
+
+func describeMetricValue(v autoscalingv2.MetricValueStatus) string {
+	switch {
+	case v.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *v.AverageUtilization)
+	case v.AverageValue != nil:
+		return fmt.Sprintf("average %s", v.AverageValue.String())
+	case v.Value != nil:
+		return v.Value.String()
+	}
+	return ""
+}
+
+// linkHPATarget mark whether the scale target of each hpa is found in the collected workloads.
+func linkHPATarget(summary *SummaryResourceUseage) {
+	hpas, ok := summary.Workloads[KindHorizontalPodAutoscaler]
+	if !ok {
+		return
+	}
+
+	targets := map[string]struct{}{}
+	add := func(kind, namespace, name string) {
+		targets[kind+"/"+namespace+"/"+name] = struct{}{}
+	}
+	for ns, list := range summary.DeploymentStatistics.List {
+		for _, item := range list {
+			add("Deployment", ns, item.Name)
+		}
+	}
+	for ns, list := range summary.StatefulsetStatistics.List {
+		for _, item := range list {
+			add("StatefulSet", ns, item.Name)
+		}
+	}
+	if replicasets, ok := summary.Workloads[KindReplicaSet]; ok {
+		for ns, list := range replicasets.List {
+			for _, item := range list {
+				add(KindReplicaSet, ns, item.(ReplicaSetStatus).Name)
+			}
+		}
+	}
+
+	for ns, list := range hpas.List {
+		for i, item := range list {
+			hpa := item.(HPAStatus)
+			_, hpa.TargetFound = targets[hpa.TargetKind+"/"+ns+"/"+hpa.TargetName]
+			list[i] = hpa
+		}
+	}
+}
+
+func derefInt32(p *int32, def int32) int32 {
+	if p == nil {
+		return def
+	}
+	return *p
+}
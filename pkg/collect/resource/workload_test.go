@@ -0,0 +1,84 @@
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBuildHPAStatus(t *testing.T) {
+	utilization := int32(80)
+	averageValue := resource.MustParse("100")
+	meta := metav1.ObjectMeta{Name: "web", Namespace: "default"}
+
+	tests := []struct {
+		name    string
+		hpa     client.Object
+		targets []string
+	}{
+		{
+			name: "v2",
+			hpa: &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: meta,
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MaxReplicas:    10,
+					Metrics: []autoscalingv2.MetricSpec{
+						{Type: autoscalingv2.ResourceMetricSourceType, Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU, Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+						}},
+						{Type: autoscalingv2.PodsMetricSourceType, Pods: &autoscalingv2.PodsMetricSource{
+							Metric: autoscalingv2.MetricIdentifier{Name: "qps"}, Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &averageValue},
+						}},
+					},
+				},
+			},
+			targets: []string{"resource cpu 80%", "pods qps average 100"},
+		},
+		{
+			name: "v2beta2",
+			hpa: &autoscalingv2beta2.HorizontalPodAutoscaler{
+				ObjectMeta: meta,
+				Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MaxReplicas:    10,
+					Metrics: []autoscalingv2beta2.MetricSpec{
+						{Type: autoscalingv2beta2.PodsMetricSourceType, Pods: &autoscalingv2beta2.PodsMetricSource{
+							Metric: autoscalingv2beta2.MetricIdentifier{Name: "qps"}, Target: autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.AverageValueMetricType, AverageValue: &averageValue},
+						}},
+					},
+				},
+			},
+			targets: []string{"pods qps average 100"},
+		},
+		{
+			name: "v1",
+			hpa: &autoscalingv1.HorizontalPodAutoscaler{
+				ObjectMeta: meta,
+				Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef:                 autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MaxReplicas:                    10,
+					TargetCPUUtilizationPercentage: &utilization,
+				},
+			},
+			targets: []string{"resource cpu 80%"},
+		},
+	}
+	for _, tt := range tests {
+		obj, ok := buildHPAStatus(tt.hpa)
+		if !ok {
+			t.Fatalf("%s: hpa not built", tt.name)
+		}
+		status := obj.(HPAStatus)
+		if status.TargetKind != "Deployment" || status.MinReplicas != 1 || status.MaxReplicas != 10 || !reflect.DeepEqual(status.TargetMetrics, tt.targets) {
+			t.Errorf("%s: unexpected status %+v", tt.name, status)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package decommission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/champly/clustermanager/pkg/lifecycle"
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/handler"
+	"github.com/symcn/pkg/clustermanager/workqueue"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var (
+	// Finalizer keeps ManagedCluster until all steps finished
+	Finalizer          = "clustermanager.io/decommission"
+	ProgressAnnotation = "clustermanager.io/decommission-progress"
+	stepRetryInterval  = time.Second * 5
+)
+
+// Progress steps finished are skipped after restart
+type Progress struct {
+	StartTime time.Time      `json:"startTime"`
+	Steps     []StepProgress `json:"steps"`
+}
+
+type StepProgress struct {
+	Name           string    `json:"name"`
+	CompletionTime time.Time `json:"completionTime"`
+}
+
+type Controller struct {
+	ctx    context.Context
+	client api.MingleClient
+	queue  api.WorkQueue
+}
+
+func New(ctx context.Context) (*Controller, error) {
+	ctrl := &Controller{
+		ctx:    ctx,
+		client: kube.ManagerPlaneClusterClient,
+	}
+
+	queue, err := workqueue.Complted(workqueue.NewQueueConfig(ctrl)).NewQueue()
+	if err != nil {
+		return nil, fmt.Errorf("Build workqueue failed:%+v", err)
+	}
+	ctrl.queue = queue
+
+	err = ctrl.client.AddResourceEventHandler(
+		&clusterapiv1.ManagedCluster{},
+		handler.NewResourceEventHandler(
+			ctrl.queue,
+			handler.NewDefaultTransformNamespacedNameEventHandler(),
+			&predicate{},
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("AddResourceEventHandler with managedcluster failed:%+v", err)
+	}
+
+	return ctrl, nil
+}
+
+func (ctrl *Controller) Start() error {
+	return ctrl.queue.Start(ctrl.ctx)
+}
+
+func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, time.Duration, error) {
+	mc := &clusterapiv1.ManagedCluster{}
+	err := ctrl.client.Get(key, mc)
+	if apierrors.IsNotFound(err) {
+		return api.Done, 0, nil
+	}
+	if err != nil {
+		return api.Done, 0, fmt.Errorf("Get ManagedCluster %s failed:%+v", key.String(), err)
+	}
+	if !isDecommissioning(mc) {
+		return api.Done, 0, nil
+	}
+	mc = mc.DeepCopy()
+
+	if !controllerutil.ContainsFinalizer(mc, Finalizer) {
+		if mc.DeletionTimestamp != nil {
+			// deleted without decommission finalizer, nothing to track
+			return api.Done, 0, nil
+		}
+		controllerutil.AddFinalizer(mc, Finalizer)
+		setProgress(mc, &Progress{StartTime: time.Now(), Steps: []StepProgress{}})
+		if err = ctrl.client.Update(mc); err != nil {
+			return api.Done, 0, fmt.Errorf("Add decommission finalizer for ManagedCluster %s failed:%+v", key.String(), err)
+		}
+		klog.Infof("Decommission ManagedCluster %s started", key.Name)
+		return api.Requeue, 0, nil
+	}
+
+	progress := getProgress(mc)
+	for _, s := range steps {
+		if progress.completed(s.name) {
+			continue
+		}
+		done, err := s.run(ctrl, mc, progress)
+		if err != nil {
+			ctrl.client.Eventf(mc, corev1.EventTypeWarning, "DecommissionStepFailed", "step %s failed: %s", s.name, err.Error())
+			return api.Done, 0, fmt.Errorf("Decommission ManagedCluster %s step %s failed:%+v", key.String(), s.name, err)
+		}
+		if !done {
+			return api.Requeue, stepRetryInterval, nil
+		}
+		progress.Steps = append(progress.Steps, StepProgress{Name: s.name, CompletionTime: time.Now()})
+		setProgress(mc, progress)
+		if err = ctrl.client.Update(mc); err != nil {
+			return api.Done, 0, fmt.Errorf("Record decommission step %s for ManagedCluster %s failed:%+v", s.name, key.String(), err)
+		}
+		klog.Infof("Decommission ManagedCluster %s step %s finished", key.Name, s.name)
+		ctrl.client.Eventf(mc, corev1.EventTypeNormal, "DecommissionStepFinished", "step %s finished", s.name)
+		return api.Requeue, 0, nil
+	}
+
+	// other finalizers such as namespace cleanup of registration controller still work after deleted
+	if mc.DeletionTimestamp == nil {
+		if err = ctrl.client.Delete(mc); err != nil && !apierrors.IsNotFound(err) {
+			return api.Done, 0, fmt.Errorf("Delete ManagedCluster %s failed:%+v", key.String(), err)
+		}
+		return api.Requeue, stepRetryInterval, nil
+	}
+	controllerutil.RemoveFinalizer(mc, Finalizer)
+	if err = ctrl.client.Update(mc); err != nil && !apierrors.IsNotFound(err) {
+		return api.Done, 0, fmt.Errorf("Remove decommission finalizer for ManagedCluster %s failed:%+v", key.String(), err)
+	}
+	klog.Infof("Decommission ManagedCluster %s finished", key.Name)
+	return api.Done, 0, nil
+}
+
+func isDecommissioning(mc *clusterapiv1.ManagedCluster) bool {
+	_, ok := mc.Annotations[lifecycle.DecommissionAnnotation]
+	return ok || controllerutil.ContainsFinalizer(mc, Finalizer)
+}
+
+func getProgress(mc *clusterapiv1.ManagedCluster) *Progress {
+	progress := &Progress{StartTime: time.Now(), Steps: []StepProgress{}}
+	if data, ok := mc.Annotations[ProgressAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), progress); err != nil {
+			klog.Warningf("parse %s of ManagedCluster %s failed, steps run again: %v", ProgressAnnotation, mc.Name, err)
+		}
+	}
+	return progress
+}
+
+func setProgress(mc *clusterapiv1.ManagedCluster, progress *Progress) {
+	data, _ := json.Marshal(progress)
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	mc.Annotations[ProgressAnnotation] = string(data)
+}
+
+func (p *Progress) completed(name string) bool {
+	for _, s := range p.Steps {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package decommission
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/joinkit"
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/champly/clustermanager/pkg/lifecycle"
+	"github.com/symcn/api"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	rtfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeClient MingleClient backed by fake clients, only methods used by controller are implemented
+type fakeClient struct {
+	api.MingleClient
+	rtClient      client.Client
+	kubeInterface kubernetes.Interface
+}
+
+func (f *fakeClient) Get(key types.NamespacedName, obj client.Object) error {
+	return f.rtClient.Get(context.TODO(), key, obj)
+}
+
+func (f *fakeClient) List(obj client.ObjectList, opts ...client.ListOption) error {
+	return f.rtClient.List(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) Update(obj client.Object, opts ...client.UpdateOption) error {
+	return f.rtClient.Update(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) Delete(obj client.Object, opts ...client.DeleteOption) error {
+	return f.rtClient.Delete(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) GetKubeInterface() kubernetes.Interface {
+	return f.kubeInterface
+}
+
+func (f *fakeClient) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+}
+
+func newFakeController(t *testing.T, objs []client.Object, kubeObjs ...runtime.Object) *Controller {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, clusterapiv1.AddToScheme, workapiv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Controller{
+		ctx: context.TODO(),
+		client: &fakeClient{
+			rtClient:      rtfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+			kubeInterface: kubefake.NewSimpleClientset(kubeObjs...),
+		},
+	}
+}
+
+func newManagedCluster(name string, progress *Progress) *clusterapiv1.ManagedCluster {
+	mc := &clusterapiv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{lifecycle.DecommissionAnnotation: ""},
+		},
+		Spec: clusterapiv1.ManagedClusterSpec{HubAcceptsClient: true},
+	}
+	if progress != nil {
+		mc.Finalizers = []string{Finalizer}
+		setProgress(mc, progress)
+	}
+	return mc
+}
+
+func completedSteps(names ...string) []StepProgress {
+	list := []StepProgress{}
+	for _, name := range names {
+		list = append(list, StepProgress{Name: name, CompletionTime: time.Now()})
+	}
+	return list
+}
+
+func stepNames(progress *Progress) []string {
+	names := []string{}
+	for _, s := range progress.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestProgress(t *testing.T) {
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	progress := &Progress{StartTime: start, Steps: completedSteps(StepDrainManifestWorks, StepDisableHubAccept)}
+	if !progress.completed(StepDisableHubAccept) || progress.completed(StepRevokeCSRs) {
+		t.Errorf("unexpected completed of %v", stepNames(progress))
+	}
+
+	// progress is only kept in annotation, a new copy of ManagedCluster is read after restart
+	mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"}}
+	setProgress(mc, progress)
+	restarted := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Annotations: mc.Annotations}}
+	got := getProgress(restarted)
+	if !got.StartTime.Equal(start) || !reflect.DeepEqual(stepNames(got), stepNames(progress)) {
+		t.Errorf("unexpected progress after restart %+v", got)
+	}
+
+	restarted.Annotations[ProgressAnnotation] = "{"
+	if got = getProgress(restarted); len(got.Steps) != 0 {
+		t.Errorf("broken progress expected run steps again, got %v", stepNames(got))
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	clusterName := "cluster-a"
+	saName := joinkit.BootstrapServiceAccountName(clusterName)
+	ctrl := newFakeController(t,
+		[]client.Object{newManagedCluster(clusterName, nil)},
+		&certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "csr-a", Labels: map[string]string{kube.ClusterNameLabel: clusterName}}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: saName, Namespace: joinkit.BootstrapNamespace}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: joinkit.BootstrapClusterRoleBindingName(saName)}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Namespace: GatewaySecretNamespace}},
+	)
+	key := types.NamespacedName{Name: clusterName}
+	get := func() *clusterapiv1.ManagedCluster {
+		mc := &clusterapiv1.ManagedCluster{}
+		if err := ctrl.client.Get(key, mc); err != nil {
+			t.Fatal(err)
+		}
+		return mc
+	}
+
+	// finalizer added first, then one step per reconcile
+	for i := 0; i <= len(steps); i++ {
+		requeue, _, err := ctrl.Reconcile(key)
+		if err != nil || requeue != api.Requeue {
+			t.Fatalf("reconcile %d: requeue %v err %v", i, requeue, err)
+		}
+		mc := get()
+		if !reflect.DeepEqual(mc.Finalizers, []string{Finalizer}) {
+			t.Fatalf("reconcile %d: unexpected finalizers %v", i, mc.Finalizers)
+		}
+		if progress := getProgress(mc); len(progress.Steps) != i {
+			t.Fatalf("reconcile %d: unexpected steps %v", i, stepNames(progress))
+		}
+	}
+
+	mc := get()
+	expected := []string{}
+	for _, s := range steps {
+		expected = append(expected, s.name)
+	}
+	if names := stepNames(getProgress(mc)); !reflect.DeepEqual(names, expected) {
+		t.Errorf("unexpected step order %v", names)
+	}
+	if mc.Spec.HubAcceptsClient {
+		t.Error("expected hubAcceptsClient disabled")
+	}
+	kubeInterface := ctrl.client.GetKubeInterface()
+	if _, err := kubeInterface.CertificatesV1().CertificateSigningRequests().Get(context.TODO(), "csr-a", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected csr deleted, got %v", err)
+	}
+	if _, err := kubeInterface.CoreV1().ServiceAccounts(joinkit.BootstrapNamespace).Get(context.TODO(), saName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected bootstrap serviceaccount deleted, got %v", err)
+	}
+	if _, err := kubeInterface.RbacV1().ClusterRoleBindings().Get(context.TODO(), joinkit.BootstrapClusterRoleBindingName(saName), metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected bootstrap clusterrolebinding deleted, got %v", err)
+	}
+	if _, err := kubeInterface.CoreV1().Secrets(GatewaySecretNamespace).Get(context.TODO(), clusterName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected cluster-gateway secret deleted, got %v", err)
+	}
+
+	// all steps finished, ManagedCluster is deleted and finalizer is kept until deletionTimestamp is set
+	if requeue, _, err := ctrl.Reconcile(key); err != nil || requeue != api.Requeue {
+		t.Fatalf("delete reconcile: requeue %v err %v", requeue, err)
+	}
+	mc = get()
+	if mc.DeletionTimestamp == nil || !reflect.DeepEqual(mc.Finalizers, []string{Finalizer}) {
+		t.Fatalf("expected deleting with finalizer, got deletionTimestamp %v finalizers %v", mc.DeletionTimestamp, mc.Finalizers)
+	}
+
+	if requeue, _, err := ctrl.Reconcile(key); err != nil || requeue != api.Done {
+		t.Fatalf("finish reconcile: requeue %v err %v", requeue, err)
+	}
+	if err := ctrl.client.Get(key, &clusterapiv1.ManagedCluster{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected ManagedCluster removed after finalizer removed, got %v", err)
+	}
+}
+
+func TestReconcileResume(t *testing.T) {
+	clusterName := "cluster-a"
+	progress := &Progress{StartTime: time.Now(), Steps: completedSteps(StepDrainManifestWorks, StepDisableHubAccept)}
+	ctrl := newFakeController(t, []client.Object{newManagedCluster(clusterName, progress)})
+	key := types.NamespacedName{Name: clusterName}
+
+	if _, _, err := ctrl.Reconcile(key); err != nil {
+		t.Fatal(err)
+	}
+	mc := &clusterapiv1.ManagedCluster{}
+	if err := ctrl.client.Get(key, mc); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{StepDrainManifestWorks, StepDisableHubAccept, StepRevokeCSRs}
+	if names := stepNames(getProgress(mc)); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected resume from %s, got %v", StepRevokeCSRs, names)
+	}
+	// finished steps are not run again
+	if !mc.Spec.HubAcceptsClient {
+		t.Errorf("expected %s skipped", StepDisableHubAccept)
+	}
+}
+
+func TestDrainManifestWorks(t *testing.T) {
+	tests := []struct {
+		name       string
+		startTime  time.Time
+		finalizers int
+	}{
+		{"waiting", time.Now(), 1},
+		{"orphan after timeout", time.Now().Add(-DrainTimeout - time.Minute), 0},
+	}
+	for _, tt := range tests {
+		clusterName := "cluster-a"
+		mw := &workapiv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{
+			Name:       "work-a",
+			Namespace:  clusterName,
+			Finalizers: []string{"cluster.open-cluster-management.io/manifest-work-cleanup"},
+		}}
+		ctrl := newFakeController(t, []client.Object{mw})
+		progress := &Progress{StartTime: tt.startTime, Steps: []StepProgress{}}
+		mc := newManagedCluster(clusterName, progress)
+
+		// first run deletes ManifestWork, work agent removes finalizer after spoke resources cleaned up
+		for i := 0; i < 2; i++ {
+			done, err := drainManifestWorks(ctrl, mc, progress)
+			if err != nil || done {
+				t.Fatalf("%s: run %d done %v err %v", tt.name, i, done, err)
+			}
+		}
+
+		got := &workapiv1.ManifestWork{}
+		err := ctrl.client.Get(types.NamespacedName{Namespace: clusterName, Name: mw.Name}, got)
+		if tt.finalizers == 0 {
+			if !apierrors.IsNotFound(err) {
+				t.Errorf("%s: expected ManifestWork orphaned, got %v finalizers %v", tt.name, err, got.Finalizers)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.DeletionTimestamp == nil || len(got.Finalizers) != tt.finalizers {
+			t.Errorf("%s: unexpected deletionTimestamp %v finalizers %v", tt.name, got.DeletionTimestamp, got.Finalizers)
+		}
+	}
+}
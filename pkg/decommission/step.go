@@ -0,0 +1,146 @@
+package decommission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/joinkit"
+	"github.com/champly/clustermanager/pkg/kube"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	StepDrainManifestWorks   = "drain-manifestworks"
+	StepDisableHubAccept     = "disable-hub-accept"
+	StepRevokeCSRs           = "revoke-csrs"
+	StepDeleteBootstrap      = "delete-bootstrap"
+	StepDeleteClusterGateway = "delete-cluster-gateway"
+	StepClearCollectCache    = "clear-collect-cache"
+)
+
+var (
+	// DrainTimeout ManifestWorks not removed by work agent in time are orphaned, resources are left on spoke
+	DrainTimeout = time.Minute * 10
+	// GatewaySecretNamespace ClusterGateway is read-only and backed by secret in --secret-namespace of cluster-gateway
+	GatewaySecretNamespace = "open-cluster-management-credentials"
+)
+
+// step returns false when not finished and should run again later.
+// ManifestWorks are drained before hubAcceptsClient disabled, work agent can't clean up spoke resources after that.
+type step struct {
+	name string
+	run  func(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error)
+}
+
+var steps = []step{
+	{name: StepDrainManifestWorks, run: drainManifestWorks},
+	{name: StepDisableHubAccept, run: disableHubAccept},
+	{name: StepRevokeCSRs, run: revokeCSRs},
+	{name: StepDeleteBootstrap, run: deleteBootstrap},
+	{name: StepDeleteClusterGateway, run: deleteClusterGateway},
+	{name: StepClearCollectCache, run: clearCollectCache},
+}
+
+func drainManifestWorks(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	works := &workapiv1.ManifestWorkList{}
+	if err := ctrl.client.List(works, client.InNamespace(mc.Name)); err != nil {
+		return false, fmt.Errorf("list ManifestWorks of cluster %s failed: %+v", mc.Name, err)
+	}
+	if len(works.Items) == 0 {
+		return true, nil
+	}
+
+	timeout := time.Since(progress.StartTime) > DrainTimeout
+	for i := range works.Items {
+		mw := &works.Items[i]
+		if mw.DeletionTimestamp == nil {
+			if err := ctrl.client.Delete(mw); err != nil && !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("delete ManifestWork %s/%s failed: %+v", mw.Namespace, mw.Name, err)
+			}
+			continue
+		}
+		if timeout && len(mw.Finalizers) > 0 {
+			klog.Warningf("ManifestWork %s/%s not drained in %s, remove finalizers and orphan resources on spoke", mw.Namespace, mw.Name, DrainTimeout)
+			mw = mw.DeepCopy()
+			mw.Finalizers = nil
+			if err := ctrl.client.Update(mw); err != nil && !apierrors.IsNotFound(err) {
+				return false, fmt.Errorf("remove finalizers of ManifestWork %s/%s failed: %+v", mw.Namespace, mw.Name, err)
+			}
+		}
+	}
+	klog.Infof("Waiting for %d ManifestWorks of cluster %s drained", len(works.Items), mc.Name)
+	return false, nil
+}
+
+func disableHubAccept(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	mc.Spec.HubAcceptsClient = false
+	return true, nil
+}
+
+// revokeCSRs CSRs of the cluster are deleted, pending ones can't be approved any more
+func revokeCSRs(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	signingRequest := ctrl.client.GetKubeInterface().CertificatesV1().CertificateSigningRequests()
+	csrs, err := signingRequest.List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.Set{kube.ClusterNameLabel: mc.Name}.String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("list CSRs of cluster %s failed: %+v", mc.Name, err)
+	}
+	for _, csr := range csrs.Items {
+		if err = signingRequest.Delete(context.TODO(), csr.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("delete CSR %s failed: %+v", csr.Name, err)
+		}
+		if !isApproved(csr) {
+			klog.Infof("Outstanding CSR %s of cluster %s revoked", csr.Name, mc.Name)
+		}
+	}
+	return true, nil
+}
+
+// deleteBootstrap ServiceAccount and ClusterRoleBinding created by join-kit, bootstrap token can't register the cluster again
+func deleteBootstrap(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	kubeInterface := ctrl.client.GetKubeInterface()
+	saName := joinkit.BootstrapServiceAccountName(mc.Name)
+
+	crbName := joinkit.BootstrapClusterRoleBindingName(saName)
+	err := kubeInterface.RbacV1().ClusterRoleBindings().Delete(context.TODO(), crbName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("delete ClusterRoleBinding %s failed: %+v", crbName, err)
+	}
+	err = kubeInterface.CoreV1().ServiceAccounts(joinkit.BootstrapNamespace).Delete(context.TODO(), saName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("delete ServiceAccount %s/%s failed: %+v", joinkit.BootstrapNamespace, saName, err)
+	}
+	return true, nil
+}
+
+func deleteClusterGateway(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	err := ctrl.client.GetKubeInterface().CoreV1().Secrets(GatewaySecretNamespace).Delete(context.TODO(), mc.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("delete cluster-gateway secret %s/%s failed: %+v", GatewaySecretNamespace, mc.Name, err)
+	}
+	return true, nil
+}
+
+func clearCollectCache(ctrl *Controller, mc *clusterapiv1.ManagedCluster, progress *Progress) (bool, error) {
+	resource.DeleteCacheWithClusterName(mc.Name)
+	return true, nil
+}
+
+func isApproved(csr certificatesv1.CertificateSigningRequest) bool {
+	for _, c := range csr.Status.Conditions {
+		if c.Type == certificatesv1.CertificateApproved {
+			return true
+		}
+	}
+	return false
+}
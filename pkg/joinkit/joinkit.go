@@ -0,0 +1,244 @@
+package joinkit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/accept"
+	"github.com/champly/clustermanager/pkg/kube"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	operatorapiv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	BootstrapNamespace   = "open-cluster-management"
+	BootstrapClusterRole = "system:open-cluster-management:bootstrap"
+	TokenTTL             = time.Hour * 24
+	// HubAPIServer the address spoke connects to, default is the host of hub kubeconfig
+	HubAPIServer        = ""
+	KlusterletNamespace = "open-cluster-management-agent"
+	BootstrapSecretName = "bootstrap-hub-kubeconfig"
+	RegistrationImage   = "quay.io/open-cluster-management/registration:v0.5.0"
+	WorkImage           = "quay.io/open-cluster-management/work:v0.5.0"
+)
+
+// Kit everything needed to join a cluster
+type Kit struct {
+	ClusterName         string
+	ExpirationTime      time.Time
+	BootstrapKubeconfig []byte
+	// OperatorManifests klusterlet operator applied on spoke before Manifests, empty when IncludeOperator is false
+	OperatorManifests []byte
+	// Manifests applied on spoke, klusterlet operator is required
+	Manifests []byte
+}
+
+// Generate create bootstrap token and pre-authorized ManagedCluster on hub, returns kit for spoke.
+func Generate(ctx context.Context, clusterName string, labels map[string]string) (*Kit, error) {
+	saName := BootstrapServiceAccountName(clusterName)
+	username := fmt.Sprintf("system:serviceaccount:%s:%s", BootstrapNamespace, saName)
+
+	if err := ensureBootstrapServiceAccount(ctx, saName); err != nil {
+		return nil, err
+	}
+	token, expiration, err := createToken(ctx, saName)
+	if err != nil {
+		return nil, err
+	}
+	if err = ensureManagedCluster(ctx, clusterName, labels, username, expiration); err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := buildBootstrapKubeconfig(kube.ManagerPlaneClusterClient.GetKubeRestConfig(), token)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := buildSpokeManifests(clusterName, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	kit := &Kit{
+		ClusterName:         clusterName,
+		ExpirationTime:      expiration,
+		BootstrapKubeconfig: kubeconfig,
+		Manifests:           manifests,
+	}
+	if IncludeOperator {
+		if kit.OperatorManifests, err = buildOperatorManifests(); err != nil {
+			return nil, err
+		}
+	}
+	return kit, nil
+}
+
+// BootstrapServiceAccountName ServiceAccount in BootstrapNamespace the bootstrap token belongs to
+func BootstrapServiceAccountName(clusterName string) string {
+	return clusterName + "-bootstrap"
+}
+
+// BootstrapClusterRoleBindingName binds BootstrapClusterRole to the bootstrap ServiceAccount
+func BootstrapClusterRoleBindingName(saName string) string {
+	return "clustermanager:bootstrap:" + saName
+}
+
+// ensureBootstrapServiceAccount one ServiceAccount per cluster, token can't be used to register other cluster
+func ensureBootstrapServiceAccount(ctx context.Context, saName string) error {
+	kubeInterface := kube.ManagerPlaneClusterClient.GetKubeInterface()
+
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: saName, Namespace: BootstrapNamespace},
+	}
+	_, err := kubeInterface.CoreV1().ServiceAccounts(BootstrapNamespace).Create(ctx, sa, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create ServiceAccount %s/%s failed: %+v", BootstrapNamespace, saName, err)
+	}
+
+	crb := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: BootstrapClusterRoleBindingName(saName)},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     BootstrapClusterRole,
+		},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: saName, Namespace: BootstrapNamespace},
+		},
+	}
+	_, err = kubeInterface.RbacV1().ClusterRoleBindings().Create(ctx, crb, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("create ClusterRoleBinding %s failed: %+v", crb.Name, err)
+	}
+	return nil
+}
+
+func createToken(ctx context.Context, saName string) (string, time.Time, error) {
+	expirationSeconds := int64(TokenTTL.Seconds())
+	tr, err := kube.ManagerPlaneClusterClient.GetKubeInterface().CoreV1().ServiceAccounts(BootstrapNamespace).CreateToken(ctx, saName, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("create token for ServiceAccount %s/%s failed: %+v", BootstrapNamespace, saName, err)
+	}
+	return tr.Status.Token, tr.Status.ExpirationTimestamp.Time, nil
+}
+
+// ensureManagedCluster pre-create ManagedCluster, existing one is pre-authorized again with new token
+func ensureManagedCluster(ctx context.Context, clusterName string, labels map[string]string, username string, expiration time.Time) error {
+	cli := kube.ManagerPlaneClusterClient
+	mc := &clusterapiv1.ManagedCluster{}
+	// read without cache, informer may not synced in command line
+	err := cli.GetCtrlRtManager().GetAPIReader().Get(ctx, types.NamespacedName{Name: clusterName}, mc)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("Get ManagedCluster %s failed: %+v", clusterName, err)
+	}
+	exist := err == nil
+	if !exist {
+		mc = &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}
+	}
+	if mc.Labels == nil {
+		mc.Labels = map[string]string{}
+	}
+	for k, v := range labels {
+		mc.Labels[k] = v
+	}
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	mc.Annotations[accept.PreAuthorizedUntilAnnotation] = expiration.Format(time.RFC3339)
+	mc.Annotations[accept.PreAuthorizedUserAnnotation] = username
+
+	if exist {
+		err = cli.Update(mc)
+	} else {
+		err = cli.Create(mc)
+	}
+	if err != nil {
+		return fmt.Errorf("pre-create ManagedCluster %s failed: %+v", clusterName, err)
+	}
+	klog.Infof("ManagedCluster %s pre-authorized for %s until %s", clusterName, username, expiration.Format(time.RFC3339))
+	return nil
+}
+
+func buildBootstrapKubeconfig(restConfig *rest.Config, token string) ([]byte, error) {
+	server := HubAPIServer
+	if server == "" {
+		server = restConfig.Host
+	}
+	caData := restConfig.CAData
+	if len(caData) == 0 && restConfig.CAFile != "" {
+		data, err := ioutil.ReadFile(restConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read hub ca file %s failed: %+v", restConfig.CAFile, err)
+		}
+		caData = data
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["hub"] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caData,
+	}
+	config.AuthInfos["bootstrap"] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts["bootstrap"] = &clientcmdapi.Context{Cluster: "hub", AuthInfo: "bootstrap"}
+	config.CurrentContext = "bootstrap"
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, fmt.Errorf("build bootstrap kubeconfig failed: %+v", err)
+	}
+	return data, nil
+}
+
+// buildSpokeManifests namespace, bootstrap secret and Klusterlet as multi-document yaml
+func buildSpokeManifests(clusterName string, kubeconfig []byte) ([]byte, error) {
+	objs := []interface{}{
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: KlusterletNamespace},
+		},
+		&corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: BootstrapSecretName, Namespace: KlusterletNamespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"kubeconfig": kubeconfig},
+		},
+		&operatorapiv1.Klusterlet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: operatorapiv1.GroupVersion.String(), Kind: "Klusterlet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "klusterlet"},
+			Spec: operatorapiv1.KlusterletSpec{
+				Namespace:                 KlusterletNamespace,
+				RegistrationImagePullSpec: RegistrationImage,
+				WorkImagePullSpec:         WorkImage,
+				ClusterName:               clusterName,
+			},
+		},
+	}
+	return marshalManifests(objs)
+}
+
+func marshalManifests(objs []interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("marshal spoke manifest failed: %+v", err)
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
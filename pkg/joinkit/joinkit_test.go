@@ -0,0 +1,68 @@
+package joinkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+type manifestMeta struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+func parseManifests(t *testing.T, data []byte) []string {
+	t.Helper()
+	list := []string{}
+	for _, doc := range strings.Split(string(data), "---\n") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		meta := manifestMeta{}
+		if err := yaml.Unmarshal([]byte(doc), &meta); err != nil {
+			t.Fatalf("invalid manifest %q: %v", doc, err)
+		}
+		list = append(list, meta.Kind+"/"+meta.Metadata.Name)
+	}
+	return list
+}
+
+func TestBuildOperatorManifests(t *testing.T) {
+	data, err := buildOperatorManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"CustomResourceDefinition/" + KlusterletCRDName,
+		"Namespace/" + OperatorNamespace,
+		"ServiceAccount/klusterlet",
+		"ClusterRole/klusterlet",
+		"ClusterRoleBinding/klusterlet",
+		"Deployment/klusterlet",
+	}
+	if got := parseManifests(t, data); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if !strings.Contains(string(data), OperatorImage) {
+		t.Errorf("operator image %s not found", OperatorImage)
+	}
+}
+
+func TestBuildSpokeManifests(t *testing.T) {
+	data, err := buildSpokeManifests("cluster-a", []byte("kubeconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Namespace/" + KlusterletNamespace, "Secret/" + BootstrapSecretName, "Klusterlet/klusterlet"}
+	if got := parseManifests(t, data); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if !strings.Contains(string(data), "clusterName: cluster-a") {
+		t.Errorf("cluster name not set in Klusterlet:\n%s", data)
+	}
+}
@@ -0,0 +1,100 @@
+package joinkit
+
+import (
+	_ "embed"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// IncludeOperator false when klusterlet operator already installed on spoke, such as by OLM
+	IncludeOperator   = true
+	OperatorNamespace = "open-cluster-management"
+	OperatorImage     = "quay.io/open-cluster-management/registration-operator:v0.5.0"
+
+	operatorName = "klusterlet"
+)
+
+// KlusterletCRDName Klusterlet of spoke manifests can't be applied before it's established,
+// manifests/klusterlets.crd.yaml is copied from open-cluster-management.io/api operator/v1 of the version in go.mod
+const KlusterletCRDName = "klusterlets.operator.open-cluster-management.io"
+
+//go:embed manifests/klusterlets.crd.yaml
+var klusterletCRD []byte
+
+// buildOperatorManifests Klusterlet CRD and the operator deploying registration and work agents from it.
+// Operator creates RBAC for agents, so it needs escalate and bind on roles.
+func buildOperatorManifests() ([]byte, error) {
+	labels := map[string]string{"app": operatorName}
+	replicas := int32(1)
+	allVerbs := []string{"create", "get", "list", "update", "watch", "patch", "delete"}
+
+	objs := []interface{}{
+		&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: OperatorNamespace},
+		},
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: metav1.ObjectMeta{Name: operatorName, Namespace: OperatorNamespace},
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: operatorName},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"secrets", "configmaps", "serviceaccounts", "namespaces"}, Verbs: allVerbs},
+				{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get", "list", "watch"}},
+				{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch", "update"}},
+				{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"subjectaccessreviews"}, Verbs: []string{"create"}},
+				{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: allVerbs},
+				{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: allVerbs},
+				{APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterrolebindings", "rolebindings"}, Verbs: allVerbs},
+				{APIGroups: []string{rbacv1.GroupName}, Resources: []string{"clusterroles", "roles"}, Verbs: append([]string{"escalate", "bind"}, allVerbs...)},
+				{APIGroups: []string{"apiextensions.k8s.io"}, Resources: []string{"customresourcedefinitions"}, Verbs: allVerbs},
+				{APIGroups: []string{"operator.open-cluster-management.io"}, Resources: []string{"klusterlets"}, Verbs: allVerbs},
+				{APIGroups: []string{"operator.open-cluster-management.io"}, Resources: []string{"klusterlets/status"}, Verbs: []string{"update", "patch"}},
+				{APIGroups: []string{"work.open-cluster-management.io"}, Resources: []string{"appliedmanifestworks"}, Verbs: allVerbs},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: operatorName},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: operatorName},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: operatorName, Namespace: OperatorNamespace}},
+		},
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: operatorName, Namespace: OperatorNamespace, Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						ServiceAccountName: operatorName,
+						Containers: []corev1.Container{
+							{
+								Name:  "registration-operator",
+								Image: OperatorImage,
+								Args:  []string{"/registration-operator", "klusterlet"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := marshalManifests(objs)
+	if err != nil {
+		return nil, err
+	}
+	if len(klusterletCRD) == 0 {
+		return nil, fmt.Errorf("klusterlet crd manifest is empty")
+	}
+	return append(append([]byte("---\n"), klusterletCRD...), data...), nil
+}
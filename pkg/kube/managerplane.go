@@ -8,9 +8,13 @@ import (
 	"github.com/symcn/pkg/clustermanager/client"
 	"github.com/symcn/pkg/clustermanager/configuration"
 	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
 )
 
 var (
+	// ClusterNameLabel set by registration on CSRs and other hub resources of a ManagedCluster
+	ClusterNameLabel = "open-cluster-management.io/cluster-name"
+
 	ManagerPlaneName          = "clustermanager"
 	ManagerPlaneClusterClient api.MingleClient
 )
@@ -18,6 +22,7 @@ var (
 func InitManagerPlaneClusterClient(ctx context.Context) (err error) {
 	opts := client.DefaultOptions()
 	clusterapiv1.AddToScheme(opts.Scheme)
+	workapiv1.AddToScheme(opts.Scheme)
 
 	ManagerPlaneClusterClient, err = client.NewMingleClient(
 		configuration.BuildDefaultClusterCfgInfo(ManagerPlaneName),
@@ -0,0 +1,206 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/kube"
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/handler"
+	"github.com/symcn/pkg/clustermanager/workqueue"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var (
+	// ResyncInterval phase depends on collect results, which don't trigger ManagedCluster events
+	ResyncInterval = time.Second * 30
+	// Finalizer keeps ManagedCluster until Removed hooks run, so they survive restart.
+	// It's removed after all other finalizers, Removed hooks run when cluster actually goes away.
+	Finalizer = "clustermanager.io/lifecycle"
+)
+
+type Controller struct {
+	ctx    context.Context
+	client api.MingleClient
+	queue  api.WorkQueue
+	hooks  *Hooks
+}
+
+func New(ctx context.Context) (*Controller, error) {
+	hooks, err := LoadHooks(HooksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &Controller{
+		ctx:    ctx,
+		client: kube.ManagerPlaneClusterClient,
+		hooks:  hooks,
+	}
+
+	queue, err := workqueue.Complted(workqueue.NewQueueConfig(ctrl)).NewQueue()
+	if err != nil {
+		return nil, fmt.Errorf("Build workqueue failed:%+v", err)
+	}
+	ctrl.queue = queue
+
+	err = ctrl.client.AddResourceEventHandler(
+		&clusterapiv1.ManagedCluster{},
+		handler.NewResourceEventHandler(
+			ctrl.queue,
+			handler.NewDefaultTransformNamespacedNameEventHandler(),
+			&predicate{},
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("AddResourceEventHandler with managedcluster failed:%+v", err)
+	}
+
+	return ctrl, nil
+}
+
+func (ctrl *Controller) Start() error {
+	return ctrl.queue.Start(ctrl.ctx)
+}
+
+func (ctrl *Controller) Reconcile(key types.NamespacedName) (api.NeedRequeue, time.Duration, error) {
+	mc := &clusterapiv1.ManagedCluster{}
+	err := ctrl.client.Get(key, mc)
+	if apierrors.IsNotFound(err) {
+		return api.Done, 0, nil
+	}
+	if err != nil {
+		return api.Done, 0, fmt.Errorf("Get ManagedCluster %s failed:%+v", key.String(), err)
+	}
+	if mc.DeletionTimestamp != nil && mc.Labels[PhaseLabel] == PhaseRemoved {
+		return ctrl.removed(mc)
+	}
+
+	csrs := &certificatesv1.CertificateSigningRequestList{}
+	err = ctrl.client.List(csrs, &client.ListOptions{
+		LabelSelector: labels.Set{kube.ClusterNameLabel: key.Name}.AsSelector(),
+	})
+	if err != nil {
+		return api.Done, 0, fmt.Errorf("Get %s CertificateSigningRequestList failed:%+v", key.String(), err)
+	}
+
+	previous := mc.Labels[PhaseLabel]
+	var health *resource.ClusterHealth
+	if h, ok := resource.GetCacheClusterHealthWithClusterName(key.Name); ok {
+		health = &h
+	}
+	phase, reason := computePhase(mc, csrs.Items, health)
+	if phase == previous {
+		if mc.DeletionTimestamp != nil {
+			return ctrl.removed(mc)
+		}
+		mc = mc.DeepCopy()
+		if !controllerutil.ContainsFinalizer(mc, Finalizer) {
+			controllerutil.AddFinalizer(mc, Finalizer)
+			if err = ctrl.client.Update(mc); err != nil {
+				return api.Done, 0, fmt.Errorf("Add lifecycle finalizer for ManagedCluster %s failed:%+v", key.String(), err)
+			}
+		}
+		if err = ctrl.runPendingHooks(mc); err != nil {
+			return api.Done, 0, err
+		}
+		return api.Requeue, ResyncInterval, nil
+	}
+
+	event := HookEvent{
+		ClusterName:   key.Name,
+		Phase:         phase,
+		PreviousPhase: previous,
+		Reason:        reason,
+		Time:          time.Now(),
+	}
+	mc = mc.DeepCopy()
+	setPhase(mc, PhaseTransition{Phase: phase, Time: event.Time, Reason: reason})
+	// hooks are recorded with phase, failed ones are retried on resync
+	setPendingHooks(mc, append(getPendingHooks(mc), ctrl.hooks.pendingHooks(event)...))
+	if mc.DeletionTimestamp == nil {
+		controllerutil.AddFinalizer(mc, Finalizer)
+	}
+	if err = ctrl.client.Update(mc); err != nil {
+		return api.Done, 0, fmt.Errorf("Set phase %s for ManagedCluster %s failed:%+v", phase, key.String(), err)
+	}
+	klog.Infof("ManagedCluster %s phase %s -> %s: %s", key.Name, previous, phase, reason)
+
+	if err = ctrl.runPendingHooks(mc); err != nil {
+		return api.Done, 0, err
+	}
+	return api.Requeue, ResyncInterval, nil
+}
+
+// removed enter Removed after other finalizers removed, lifecycle finalizer is removed
+// once all pending hooks finished. Hooks may run again if removing finalizer failed.
+func (ctrl *Controller) removed(mc *clusterapiv1.ManagedCluster) (api.NeedRequeue, time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(mc, Finalizer) {
+		// deleted before finalizer added, nothing to track
+		return api.Done, 0, nil
+	}
+	mc = mc.DeepCopy()
+	if len(mc.Finalizers) > 1 {
+		klog.V(4).Infof("ManagedCluster %s waiting for finalizers %v", mc.Name, mc.Finalizers)
+		if err := ctrl.runPendingHooks(mc); err != nil {
+			return api.Done, 0, err
+		}
+		return api.Requeue, ResyncInterval, nil
+	}
+
+	if mc.Labels[PhaseLabel] != PhaseRemoved {
+		event := HookEvent{
+			ClusterName:   mc.Name,
+			Phase:         PhaseRemoved,
+			PreviousPhase: mc.Labels[PhaseLabel],
+			Reason:        "ManagedCluster deleted",
+			Time:          time.Now(),
+		}
+		setPhase(mc, PhaseTransition{Phase: PhaseRemoved, Time: event.Time, Reason: event.Reason})
+		setPendingHooks(mc, append(getPendingHooks(mc), ctrl.hooks.pendingHooks(event)...))
+		if err := ctrl.client.Update(mc); err != nil {
+			return api.Done, 0, fmt.Errorf("Set phase %s for ManagedCluster %s failed:%+v", PhaseRemoved, mc.Name, err)
+		}
+		klog.Infof("ManagedCluster %s phase %s -> %s: %s", mc.Name, event.PreviousPhase, PhaseRemoved, event.Reason)
+	}
+	if err := ctrl.runPendingHooks(mc); err != nil {
+		return api.Done, 0, err
+	}
+	if len(getPendingHooks(mc)) > 0 {
+		return api.Requeue, ResyncInterval, nil
+	}
+
+	controllerutil.RemoveFinalizer(mc, Finalizer)
+	if err := ctrl.client.Update(mc); err != nil && !apierrors.IsNotFound(err) {
+		return api.Done, 0, fmt.Errorf("Remove lifecycle finalizer for ManagedCluster %s failed:%+v", mc.Name, err)
+	}
+	return api.Done, 0, nil
+}
+
+// runPendingHooks mc is updated with hooks still failed, failure is reported as event
+func (ctrl *Controller) runPendingHooks(mc *clusterapiv1.ManagedCluster) error {
+	pending := getPendingHooks(mc)
+	if len(pending) == 0 {
+		return nil
+	}
+	remaining, err := runPendingHooks(ctrl.client, pending)
+	if err != nil {
+		klog.Error(err)
+		ctrl.client.Eventf(mc, corev1.EventTypeWarning, "LifecycleHookFailed", "%s", err.Error())
+	}
+	setPendingHooks(mc, remaining)
+	if err = ctrl.client.Update(mc); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("Record pending hooks for ManagedCluster %s failed:%+v", mc.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/symcn/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	rtfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeClient MingleClient backed by fake client, only methods used by controller are implemented
+type fakeClient struct {
+	api.MingleClient
+	rtClient client.Client
+}
+
+func (f *fakeClient) Get(key types.NamespacedName, obj client.Object) error {
+	return f.rtClient.Get(context.TODO(), key, obj)
+}
+
+func (f *fakeClient) List(obj client.ObjectList, opts ...client.ListOption) error {
+	return f.rtClient.List(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) Update(obj client.Object, opts ...client.UpdateOption) error {
+	return f.rtClient.Update(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) Delete(obj client.Object, opts ...client.DeleteOption) error {
+	return f.rtClient.Delete(context.TODO(), obj, opts...)
+}
+
+func (f *fakeClient) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+}
+
+// webhook fails the first failures calls
+func newWebhook(failures int) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	return server, &calls
+}
+
+func newFakeController(t *testing.T, hooks *Hooks, objs ...client.Object) *Controller {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, clusterapiv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Controller{
+		ctx:    context.TODO(),
+		client: &fakeClient{rtClient: rtfake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()},
+		hooks:  hooks,
+	}
+}
+
+func getManagedCluster(t *testing.T, ctrl *Controller, key types.NamespacedName) *clusterapiv1.ManagedCluster {
+	mc := &clusterapiv1.ManagedCluster{}
+	if err := ctrl.client.Get(key, mc); err != nil {
+		t.Fatal(err)
+	}
+	return mc
+}
+
+func TestReconcilePendingHooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		reconciles  int
+		calls       int
+		pending     int
+	}{
+		{"succeeded", 0, 10, 2, 1, 0},
+		{"retried on resync", 1, 10, 2, 2, 0},
+		{"still failing", 5, 10, 3, 3, 1},
+		{"dropped after max attempts", 5, 2, 3, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newWebhook(tt.failures)
+			defer server.Close()
+			defer func(n int) { MaxHookAttempts = n }(MaxHookAttempts)
+			MaxHookAttempts = tt.maxAttempts
+
+			key := types.NamespacedName{Name: "cluster-a"}
+			ctrl := newFakeController(t, &Hooks{Hooks: []Hook{{Phase: PhasePending, Webhook: server.URL}}},
+				&clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: key.Name}})
+			for i := 0; i < tt.reconciles; i++ {
+				if _, _, err := ctrl.Reconcile(key); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mc := getManagedCluster(t, ctrl, key)
+			if mc.Labels[PhaseLabel] != PhasePending {
+				t.Errorf("unexpected phase %s", mc.Labels[PhaseLabel])
+			}
+			if *calls != tt.calls {
+				t.Errorf("expected webhook called %d times, got %d", tt.calls, *calls)
+			}
+			if pending := getPendingHooks(mc); len(pending) != tt.pending {
+				t.Errorf("expected %d pending hooks, got %+v", tt.pending, pending)
+			}
+		})
+	}
+}
+
+func TestReconcileRemoved(t *testing.T) {
+	server, calls := newWebhook(1)
+	defer server.Close()
+
+	key := types.NamespacedName{Name: "cluster-a"}
+	mc := &clusterapiv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:       key.Name,
+		Labels:     map[string]string{PhaseLabel: PhaseDecommissioning},
+		Finalizers: []string{Finalizer},
+	}}
+	ctrl := newFakeController(t, &Hooks{Hooks: []Hook{{Phase: PhaseRemoved, Webhook: server.URL}}}, mc)
+	if err := ctrl.client.Delete(mc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removed hook failed, finalizer is kept until it's retried
+	if requeue, _, err := ctrl.Reconcile(key); err != nil || requeue != api.Requeue {
+		t.Fatalf("requeue %v err %v", requeue, err)
+	}
+	mc = getManagedCluster(t, ctrl, key)
+	if mc.Labels[PhaseLabel] != PhaseRemoved || len(getPendingHooks(mc)) != 1 || len(mc.Finalizers) != 1 {
+		t.Fatalf("unexpected phase %s pending %v finalizers %v", mc.Labels[PhaseLabel], getPendingHooks(mc), mc.Finalizers)
+	}
+
+	if requeue, _, err := ctrl.Reconcile(key); err != nil || requeue != api.Done {
+		t.Fatalf("requeue %v err %v", requeue, err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected webhook called twice, got %d", *calls)
+	}
+	if err := ctrl.client.Get(key, &clusterapiv1.ManagedCluster{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected ManagedCluster removed after finalizer removed, got %v", err)
+	}
+}
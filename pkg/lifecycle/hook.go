@@ -0,0 +1,208 @@
+package lifecycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/symcn/api"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+	workapiv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// HooksFile yaml or json file contains Hooks
+	HooksFile      = ""
+	WebhookTimeout = time.Second * 10
+	// PendingHooksAnnotation hooks not succeeded yet, retried on resync and survive restart
+	PendingHooksAnnotation = "clustermanager.io/pending-hooks"
+	// MaxHookAttempts pending hook is dropped after failed so many times
+	MaxHookAttempts = 10
+)
+
+// Hooks run when cluster entering a phase
+//
+//	hooks:
+//	- phase: Joined
+//	  manifestWork: /etc/clustermanager/hooks/addons.yaml
+//	- phase: Unreachable
+//	  webhook: https://alert.example.com/clustermanager
+type Hooks struct {
+	Hooks []Hook `json:"hooks"`
+}
+
+type Hook struct {
+	Phase string `json:"phase"`
+	// Webhook receives HookEvent with POST
+	Webhook string `json:"webhook"`
+	// ManifestWork yaml file of ManifestWork, rendered as text/template with HookEvent,
+	// created in the namespace of cluster
+	ManifestWork string `json:"manifestWork"`
+}
+
+// PendingHook hook with the event triggered it
+type PendingHook struct {
+	Hook     Hook      `json:"hook"`
+	Event    HookEvent `json:"event"`
+	Attempts int       `json:"attempts"`
+}
+
+type HookEvent struct {
+	ClusterName   string    `json:"clusterName"`
+	Phase         string    `json:"phase"`
+	PreviousPhase string    `json:"previousPhase"`
+	Reason        string    `json:"reason"`
+	Time          time.Time `json:"time"`
+}
+
+func LoadHooks(file string) (*Hooks, error) {
+	hooks := &Hooks{}
+	if file == "" {
+		return hooks, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read lifecycle hooks file %s failed: %+v", file, err)
+	}
+	if err = yaml.Unmarshal(data, hooks); err != nil {
+		return nil, fmt.Errorf("parse lifecycle hooks file %s failed: %+v", file, err)
+	}
+	for _, hook := range hooks.Hooks {
+		if !isValidPhase(hook.Phase) {
+			return nil, fmt.Errorf("lifecycle hook with unknown phase %s", hook.Phase)
+		}
+	}
+	return hooks, nil
+}
+
+// pendingHooks hooks of the phase entered by event
+func (h *Hooks) pendingHooks(event HookEvent) []PendingHook {
+	list := []PendingHook{}
+	for _, hook := range h.Hooks {
+		if hook.Phase == event.Phase {
+			list = append(list, PendingHook{Hook: hook, Event: event})
+		}
+	}
+	return list
+}
+
+// runPendingHooks returns hooks still failed, hooks failed MaxHookAttempts times are dropped, errors are joined.
+func runPendingHooks(cli api.MingleClient, pending []PendingHook) ([]PendingHook, error) {
+	remaining := []PendingHook{}
+	var errs []string
+	for _, ph := range pending {
+		err := runHook(cli, ph.Hook, ph.Event)
+		if err == nil {
+			continue
+		}
+		ph.Attempts++
+		if ph.Attempts >= MaxHookAttempts {
+			errs = append(errs, fmt.Sprintf("%s hook for cluster %s dropped after %d attempts: %s", ph.Event.Phase, ph.Event.ClusterName, ph.Attempts, err.Error()))
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("%s hook for cluster %s failed: %s", ph.Event.Phase, ph.Event.ClusterName, err.Error()))
+		remaining = append(remaining, ph)
+	}
+	if len(errs) > 0 {
+		return remaining, fmt.Errorf("run lifecycle hooks failed: %v", errs)
+	}
+	return remaining, nil
+}
+
+func runHook(cli api.MingleClient, hook Hook, event HookEvent) error {
+	var errs []string
+	if hook.Webhook != "" {
+		if err := callWebhook(hook.Webhook, event); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if hook.ManifestWork != "" {
+		if err := createManifestWork(cli, hook.ManifestWork, event); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func getPendingHooks(mc *clusterapiv1.ManagedCluster) []PendingHook {
+	pending := []PendingHook{}
+	if data, ok := mc.Annotations[PendingHooksAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &pending); err != nil {
+			klog.Warningf("parse %s of ManagedCluster %s failed, pending hooks dropped: %v", PendingHooksAnnotation, mc.Name, err)
+			return []PendingHook{}
+		}
+	}
+	return pending
+}
+
+func setPendingHooks(mc *clusterapiv1.ManagedCluster, pending []PendingHook) {
+	if len(pending) == 0 {
+		delete(mc.Annotations, PendingHooksAnnotation)
+		return
+	}
+	data, _ := json.Marshal(pending)
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	mc.Annotations[PendingHooksAnnotation] = string(data)
+}
+
+func callWebhook(url string, event HookEvent) error {
+	data, _ := json.Marshal(event)
+	client := &http.Client{Timeout: WebhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("call webhook %s failed: %+v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("call webhook %s failed: status code %d", url, resp.StatusCode)
+	}
+	klog.Infof("Webhook %s called for cluster %s entering %s", url, event.ClusterName, event.Phase)
+	return nil
+}
+
+func createManifestWork(cli api.MingleClient, file string, event HookEvent) error {
+	tmpl, err := template.ParseFiles(file)
+	if err != nil {
+		return fmt.Errorf("parse manifestwork template %s failed: %+v", file, err)
+	}
+	buf := &bytes.Buffer{}
+	if err = tmpl.Execute(buf, event); err != nil {
+		return fmt.Errorf("render manifestwork template %s failed: %+v", file, err)
+	}
+	mw := &workapiv1.ManifestWork{}
+	if err = yaml.Unmarshal(buf.Bytes(), mw); err != nil {
+		return fmt.Errorf("parse manifestwork %s failed: %+v", file, err)
+	}
+	mw.Namespace = event.ClusterName
+
+	err = cli.Create(mw)
+	if apierrors.IsAlreadyExists(err) {
+		klog.Infof("ManifestWork %s/%s already exists", mw.Namespace, mw.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("create ManifestWork %s/%s failed: %+v", mw.Namespace, mw.Name, err)
+	}
+	klog.Infof("ManifestWork %s/%s created for cluster %s entering %s", mw.Namespace, mw.Name, event.ClusterName, event.Phase)
+	return nil
+}
+
+func isValidPhase(phase string) bool {
+	switch phase {
+	case PhasePending, PhaseApproved, PhaseJoined, PhaseAvailable, PhaseDegraded, PhaseUnreachable, PhaseDecommissioning, PhaseRemoved:
+		return true
+	}
+	return false
+}
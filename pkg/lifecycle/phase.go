@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	PhasePending         = "Pending"
+	PhaseApproved        = "Approved"
+	PhaseJoined          = "Joined"
+	PhaseAvailable       = "Available"
+	PhaseDegraded        = "Degraded"
+	PhaseUnreachable     = "Unreachable"
+	PhaseDecommissioning = "Decommissioning"
+	PhaseRemoved         = "Removed"
+)
+
+var (
+	// PhaseLabel phase is recorded as label, so clusters can be selected by phase
+	PhaseLabel                    = "clustermanager.io/phase"
+	PhaseTransitionTimeAnnotation = "clustermanager.io/phase-transition-time"
+	PhaseReasonAnnotation         = "clustermanager.io/phase-reason"
+	PhaseHistoryAnnotation        = "clustermanager.io/phase-history"
+	// DecommissionAnnotation ManagedCluster with it enters Decommissioning
+	DecommissionAnnotation = "clustermanager.io/decommission"
+	// MaxPhaseHistory the count of latest transitions kept in history annotation
+	MaxPhaseHistory = 10
+)
+
+type PhaseTransition struct {
+	Phase string    `json:"phase"`
+	Time  time.Time `json:"time"`
+	// Reason why entered the phase
+	Reason string `json:"reason"`
+}
+
+// computePhase derive phase from deletion, CSR approval, ManagedCluster conditions and collect health,
+// later phase in lifecycle takes precedence. health is nil when cluster not probed yet.
+func computePhase(mc *clusterapiv1.ManagedCluster, csrs []certificatesv1.CertificateSigningRequest, health *resource.ClusterHealth) (phase, reason string) {
+	if mc.DeletionTimestamp != nil {
+		return PhaseDecommissioning, "ManagedCluster is being deleted"
+	}
+	if _, ok := mc.Annotations[DecommissionAnnotation]; ok {
+		return PhaseDecommissioning, "decommission annotation is set"
+	}
+
+	if !meta.IsStatusConditionTrue(mc.Status.Conditions, clusterapiv1.ManagedClusterConditionJoined) {
+		if hasApprovedCSR(csrs) {
+			return PhaseApproved, "CSR approved"
+		}
+		if mc.Spec.HubAcceptsClient {
+			return PhaseApproved, "hubAcceptsClient is true"
+		}
+		return PhasePending, "waiting for hub to accept cluster"
+	}
+
+	available := meta.FindStatusCondition(mc.Status.Conditions, clusterapiv1.ManagedClusterConditionAvailable)
+	if available == nil {
+		return PhaseJoined, "ManagedCluster joined, availability not reported yet"
+	}
+	if available.Status != metav1.ConditionTrue {
+		return PhaseUnreachable, "ManagedClusterConditionAvailable is " + string(available.Status) + ": " + available.Message
+	}
+
+	if health == nil {
+		return PhaseAvailable, "ManagedClusterConditionAvailable is True"
+	}
+	switch health.State {
+	case resource.HealthStateUnreachable:
+		return PhaseUnreachable, "collect probe is Unreachable: " + joinReasons(health.Reasons)
+	case resource.HealthStateDegraded, resource.HealthStateUnhealthy:
+		return PhaseDegraded, "collect health is " + health.State + ": " + joinReasons(health.Reasons)
+	}
+	return PhaseAvailable, "ManagedClusterConditionAvailable is True and collect health is " + health.State
+}
+
+func hasApprovedCSR(csrs []certificatesv1.CertificateSigningRequest) bool {
+	for _, csr := range csrs {
+		for _, c := range csr.Status.Conditions {
+			if c.Type == certificatesv1.CertificateApproved && c.Status == corev1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinReasons(reasons []string) string {
+	return strings.Join(reasons, "; ")
+}
+
+// getPhaseHistory invalid history is dropped, it's informational only
+func getPhaseHistory(mc *clusterapiv1.ManagedCluster) []PhaseTransition {
+	history := []PhaseTransition{}
+	if data, ok := mc.Annotations[PhaseHistoryAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &history); err != nil {
+			return []PhaseTransition{}
+		}
+	}
+	return history
+}
+
+// setPhase record transition on ManagedCluster labels and annotations
+func setPhase(mc *clusterapiv1.ManagedCluster, transition PhaseTransition) {
+	history := append(getPhaseHistory(mc), transition)
+	if len(history) > MaxPhaseHistory {
+		history = history[len(history)-MaxPhaseHistory:]
+	}
+	data, _ := json.Marshal(history)
+
+	if mc.Labels == nil {
+		mc.Labels = map[string]string{}
+	}
+	if mc.Annotations == nil {
+		mc.Annotations = map[string]string{}
+	}
+	mc.Labels[PhaseLabel] = transition.Phase
+	mc.Annotations[PhaseTransitionTimeAnnotation] = transition.Time.Format(time.RFC3339)
+	mc.Annotations[PhaseReasonAnnotation] = transition.Reason
+	mc.Annotations[PhaseHistoryAnnotation] = string(data)
+}
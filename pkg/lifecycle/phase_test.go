@@ -0,0 +1,97 @@
+package lifecycle
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterapiv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestComputePhase(t *testing.T) {
+	condition := func(conditionType string, status metav1.ConditionStatus) metav1.Condition {
+		return metav1.Condition{Type: conditionType, Status: status, Message: "lease expired"}
+	}
+	cluster := func(accepted bool, conditions ...metav1.Condition) *clusterapiv1.ManagedCluster {
+		return &clusterapiv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"},
+			Spec:       clusterapiv1.ManagedClusterSpec{HubAcceptsClient: accepted},
+			Status:     clusterapiv1.ManagedClusterStatus{Conditions: conditions},
+		}
+	}
+	joined := condition(clusterapiv1.ManagedClusterConditionJoined, metav1.ConditionTrue)
+	available := condition(clusterapiv1.ManagedClusterConditionAvailable, metav1.ConditionTrue)
+
+	deleting := cluster(true, joined, available)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	annotated := cluster(true, joined, available)
+	annotated.Annotations = map[string]string{DecommissionAnnotation: ""}
+
+	csr := func(status corev1.ConditionStatus) certificatesv1.CertificateSigningRequest {
+		return certificatesv1.CertificateSigningRequest{
+			Status: certificatesv1.CertificateSigningRequestStatus{
+				Conditions: []certificatesv1.CertificateSigningRequestCondition{{Type: certificatesv1.CertificateApproved, Status: status}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mc     *clusterapiv1.ManagedCluster
+		csrs   []certificatesv1.CertificateSigningRequest
+		health *resource.ClusterHealth
+		phase  string
+		reason string
+	}{
+		{name: "pending", mc: cluster(false), phase: PhasePending},
+		{name: "csr approved", mc: cluster(false), csrs: []certificatesv1.CertificateSigningRequest{csr(corev1.ConditionTrue)}, phase: PhaseApproved, reason: "CSR approved"},
+		{name: "csr approved condition false", mc: cluster(false), csrs: []certificatesv1.CertificateSigningRequest{csr(corev1.ConditionFalse)}, phase: PhasePending},
+		{name: "accepted", mc: cluster(true), phase: PhaseApproved, reason: "hubAcceptsClient"},
+		{name: "joined without availability", mc: cluster(true, joined), phase: PhaseJoined},
+		{name: "unavailable", mc: cluster(true, joined, condition(clusterapiv1.ManagedClusterConditionAvailable, metav1.ConditionUnknown)), phase: PhaseUnreachable, reason: "lease expired"},
+		{name: "available not probed", mc: cluster(true, joined, available), phase: PhaseAvailable},
+		{name: "available healthy", mc: cluster(true, joined, available), health: &resource.ClusterHealth{State: resource.HealthStateHealthy}, phase: PhaseAvailable, reason: resource.HealthStateHealthy},
+		{name: "degraded", mc: cluster(true, joined, available), health: &resource.ClusterHealth{State: resource.HealthStateDegraded, Reasons: []string{"etcd not ready"}}, phase: PhaseDegraded, reason: "etcd not ready"},
+		{name: "unhealthy", mc: cluster(true, joined, available), health: &resource.ClusterHealth{State: resource.HealthStateUnhealthy}, phase: PhaseDegraded},
+		{name: "probe unreachable", mc: cluster(true, joined, available), health: &resource.ClusterHealth{State: resource.HealthStateUnreachable}, phase: PhaseUnreachable, reason: "collect probe"},
+		{name: "deleting", mc: deleting, phase: PhaseDecommissioning, reason: "deleted"},
+		{name: "decommission annotation", mc: annotated, health: &resource.ClusterHealth{State: resource.HealthStateHealthy}, phase: PhaseDecommissioning, reason: "annotation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase, reason := computePhase(tt.mc, tt.csrs, tt.health)
+			if phase != tt.phase {
+				t.Errorf("expected phase %s, got %s: %s", tt.phase, phase, reason)
+			}
+			if !strings.Contains(reason, tt.reason) {
+				t.Errorf("expected reason contains %q, got %q", tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestSetPhase(t *testing.T) {
+	mc := &clusterapiv1.ManagedCluster{}
+	now := time.Now()
+	for i := 0; i < MaxPhaseHistory+2; i++ {
+		setPhase(mc, PhaseTransition{Phase: PhaseJoined, Time: now, Reason: "joined"})
+	}
+	setPhase(mc, PhaseTransition{Phase: PhaseAvailable, Time: now, Reason: "available"})
+
+	if mc.Labels[PhaseLabel] != PhaseAvailable || mc.Annotations[PhaseReasonAnnotation] != "available" {
+		t.Errorf("unexpected phase %s reason %s", mc.Labels[PhaseLabel], mc.Annotations[PhaseReasonAnnotation])
+	}
+	history := getPhaseHistory(mc)
+	if len(history) != MaxPhaseHistory || history[len(history)-1].Phase != PhaseAvailable {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	mc.Annotations[PhaseHistoryAnnotation] = "{"
+	if history = getPhaseHistory(mc); len(history) != 0 {
+		t.Errorf("invalid history should be dropped, got %+v", history)
+	}
+}
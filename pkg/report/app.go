@@ -0,0 +1,197 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+type AppView struct {
+	Name          string
+	Clusters      []string
+	Replicas      int32
+	ReadyReplicas int32
+	Requests      corev1.ResourceList
+	// ImageVersions image repository -> tag -> clusters
+	ImageVersions map[string]map[string][]string
+	ImageSkew     bool
+	Workloads     []AppWorkload
+}
+
+type AppWorkload struct {
+	ClusterName   string
+	Namespace     string
+	Kind          string
+	Name          string
+	Replicas      int32
+	ReadyReplicas int32
+	Images        []string
+}
+
+type appWorkload struct {
+	AppWorkload
+	app      string
+	requests corev1.ResourceList
+	images   []resource.ContainerImage
+}
+
+// BuildAppReport aggregate workloads of all clusters by ShowName,
+// workloads without ShowName are ignored.
+func BuildAppReport(list []resource.SummaryResourceUseage) []AppView {
+	apps := map[string]*AppView{}
+	for _, sru := range list {
+		for _, w := range flattenAppWorkloads(sru) {
+			if w.app == "" {
+				continue
+			}
+			app, ok := apps[w.app]
+			if !ok {
+				app = &AppView{
+					Name:          w.app,
+					Clusters:      []string{},
+					Requests:      corev1.ResourceList{},
+					ImageVersions: map[string]map[string][]string{},
+					Workloads:     []AppWorkload{},
+				}
+				apps[w.app] = app
+			}
+			app.add(w)
+		}
+	}
+
+	result := make([]AppView, 0, len(apps))
+	for _, app := range apps {
+		app.complete()
+		result = append(result, *app)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func (app *AppView) add(w appWorkload) {
+	app.Workloads = append(app.Workloads, w.AppWorkload)
+	app.Replicas += w.Replicas
+	app.ReadyReplicas += w.ReadyReplicas
+	resource.AddResourceList(app.Requests, multiplyResourceList(w.requests, int64(w.Replicas)))
+
+	for _, image := range w.images {
+		tag := image.Tag
+		if tag == "" {
+			tag = image.Digest
+		}
+		tags, ok := app.ImageVersions[image.Repository]
+		if !ok {
+			tags = map[string][]string{}
+			app.ImageVersions[image.Repository] = tags
+		}
+		if !containsString(tags[tag], w.ClusterName) {
+			tags[tag] = append(tags[tag], w.ClusterName)
+		}
+	}
+	if !containsString(app.Clusters, w.ClusterName) {
+		app.Clusters = append(app.Clusters, w.ClusterName)
+	}
+}
+
+func (app *AppView) complete() {
+	sort.Strings(app.Clusters)
+	for _, tags := range app.ImageVersions {
+		for _, clusters := range tags {
+			sort.Strings(clusters)
+		}
+		if imageSkew(tags) {
+			app.ImageSkew = true
+		}
+	}
+	sort.Slice(app.Workloads, func(i, j int) bool {
+		a, b := app.Workloads[i], app.Workloads[j]
+		if a.ClusterName != b.ClusterName {
+			return a.ClusterName < b.ClusterName
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Kind+a.Name < b.Kind+b.Name
+	})
+}
+
+// imageSkew clusters run different tag sets of the same repository, several tags
+// in one cluster such as canary are not skew if every cluster runs the same set.
+func imageSkew(tags map[string][]string) bool {
+	clusterTags := map[string][]string{}
+	for tag, clusters := range tags {
+		for _, clusterName := range clusters {
+			clusterTags[clusterName] = append(clusterTags[clusterName], tag)
+		}
+	}
+	tagSets := map[string]struct{}{}
+	for _, list := range clusterTags {
+		sort.Strings(list)
+		tagSets[strings.Join(list, ",")] = struct{}{}
+	}
+	return len(tagSets) > 1
+}
+
+func flattenAppWorkloads(sru resource.SummaryResourceUseage) []appWorkload {
+	list := []appWorkload{}
+	for ns, items := range sru.DeploymentStatistics.List {
+		for _, item := range items {
+			list = append(list, newAppWorkload(sru.ClusterName, ns, "Deployment", item.Name, item.ShowName, item.Replicas, item.ReadyReplicas, item.Resource, item.Rollout))
+		}
+	}
+	for ns, items := range sru.StatefulsetStatistics.List {
+		for _, item := range items {
+			list = append(list, newAppWorkload(sru.ClusterName, ns, "StatefulSet", item.Name, item.ShowName, item.Replicas, item.ReadyReplicas, item.Resource, item.Rollout))
+		}
+	}
+	for ns, items := range sru.DaemonsetStatistics.List {
+		for _, item := range items {
+			list = append(list, newAppWorkload(sru.ClusterName, ns, "DaemonSet", item.Name, item.ShowName, item.DesiredNumberScheduled, item.NumberReady, item.Resource, item.Rollout))
+		}
+	}
+	return list
+}
+
+func newAppWorkload(clusterName, namespace, kind, name, app string, replicas, ready int32, rs resource.Resouces, rollout resource.RolloutStatus) appWorkload {
+	images := make([]string, 0, len(rollout.Images))
+	for _, image := range rollout.Images {
+		images = append(images, image.Image)
+	}
+	return appWorkload{
+		AppWorkload: AppWorkload{
+			ClusterName:   clusterName,
+			Namespace:     namespace,
+			Kind:          kind,
+			Name:          name,
+			Replicas:      replicas,
+			ReadyReplicas: ready,
+			Images:        images,
+		},
+		app:      app,
+		requests: rs.Requests,
+		images:   rollout.Images,
+	}
+}
+
+// multiplyResourceList returns new list with every quantity multiplied by n
+func multiplyResourceList(list corev1.ResourceList, n int64) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for name, value := range list {
+		result[name] = *apiresource.NewMilliQuantity(value.MilliValue()*n, value.Format)
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package report
+
+import "testing"
+
+func TestImageSkew(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string][]string
+		skew bool
+	}{
+		{"single tag", map[string][]string{"v1": {"cluster-a", "cluster-b"}}, false},
+		{"different tags", map[string][]string{"v1": {"cluster-a"}, "v2": {"cluster-b"}}, true},
+		{"same canary tags in every cluster", map[string][]string{"v1": {"cluster-a", "cluster-b"}, "v2": {"cluster-a", "cluster-b"}}, false},
+		{"canary in one cluster", map[string][]string{"v1": {"cluster-a", "cluster-b"}, "v2": {"cluster-a"}}, true},
+		{"multiple tags in single cluster", map[string][]string{"v1": {"cluster-a"}, "v2": {"cluster-a"}}, false},
+	}
+	for _, tt := range tests {
+		if skew := imageSkew(tt.tags); skew != tt.skew {
+			t.Errorf("%s: expected skew %v, got %v", tt.name, tt.skew, skew)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type CapacityReport struct {
+	Capacity    corev1.ResourceList
+	Allocatable corev1.ResourceList
+	Requested   corev1.ResourceList
+	Clusters    []ClusterCapacity
+}
+
+type ClusterCapacity struct {
+	ClusterName  string
+	Capacity     corev1.ResourceList
+	Allocatable  corev1.ResourceList
+	Requested    corev1.ResourceList
+	Headroom     corev1.ResourceList
+	RequestRatio map[corev1.ResourceName]float64
+}
+
+// BuildCapacityReport sum capacity, allocatable and requested of every resource name across the fleet.
+func BuildCapacityReport(list []resource.ClusterStatus) CapacityReport {
+	report := CapacityReport{
+		Capacity:    corev1.ResourceList{},
+		Allocatable: corev1.ResourceList{},
+		Requested:   corev1.ResourceList{},
+		Clusters:    make([]ClusterCapacity, 0, len(list)),
+	}
+	for _, cs := range list {
+		resource.AddResourceList(report.Capacity, cs.Capacity)
+		resource.AddResourceList(report.Allocatable, cs.Allocatable)
+		resource.AddResourceList(report.Requested, cs.Requested)
+		report.Clusters = append(report.Clusters, ClusterCapacity{
+			ClusterName:  cs.ClusterName,
+			Capacity:     cs.Capacity,
+			Allocatable:  cs.Allocatable,
+			Requested:    cs.Requested,
+			Headroom:     cs.Utilization.Headroom,
+			RequestRatio: cs.Utilization.RequestRatio,
+		})
+	}
+	return report
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestBuildCapacityReport(t *testing.T) {
+	list := []resource.ClusterStatus{
+		{
+			ClusterName: "cluster-a",
+			Capacity:    corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("8"), "nvidia.com/gpu": apiresource.MustParse("4")},
+			Allocatable: corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("7500m"), "nvidia.com/gpu": apiresource.MustParse("4")},
+			Requested:   corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("2"), "nvidia.com/gpu": apiresource.MustParse("1")},
+		},
+		{
+			ClusterName: "cluster-b",
+			Capacity:    corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("16")},
+			Allocatable: corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("15")},
+			Requested:   corev1.ResourceList{corev1.ResourceCPU: apiresource.MustParse("500m")},
+		},
+	}
+
+	report := BuildCapacityReport(list)
+	tests := []struct {
+		name     string
+		list     corev1.ResourceList
+		resource corev1.ResourceName
+		expected string
+	}{
+		{"capacity", report.Capacity, corev1.ResourceCPU, "24"},
+		{"capacity", report.Capacity, "nvidia.com/gpu", "4"},
+		{"allocatable", report.Allocatable, corev1.ResourceCPU, "22500m"},
+		{"requested", report.Requested, corev1.ResourceCPU, "2500m"},
+		{"requested", report.Requested, "nvidia.com/gpu", "1"},
+	}
+	for _, tt := range tests {
+		q := tt.list[tt.resource]
+		if q.Cmp(apiresource.MustParse(tt.expected)) != 0 {
+			t.Errorf("%s %s expected %s, got %s", tt.name, tt.resource, tt.expected, q.String())
+		}
+	}
+	if len(report.Clusters) != 2 || report.Clusters[1].ClusterName != "cluster-b" {
+		t.Errorf("unexpected clusters %+v", report.Clusters)
+	}
+	// source cluster status must not be modified by summing
+	if q := list[0].Capacity[corev1.ResourceCPU]; q.Cmp(apiresource.MustParse("8")) != 0 {
+		t.Errorf("cluster-a capacity modified to %s", q.String())
+	}
+}
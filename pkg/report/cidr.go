@@ -0,0 +1,215 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+var (
+	// CIDRSupernet is the address plan all cluster ranges are allocated from,
+	// free ranges are only suggested when it is set.
+	CIDRSupernet = ""
+	// CIDRSuggestPrefixLen is the prefix length of the suggested free ranges.
+	CIDRSuggestPrefixLen = 16
+	// CIDRSuggestCount is the max count of the suggested free ranges.
+	CIDRSuggestCount = 10
+
+	// maxScanSubnets limit the subnets scanned in supernet, avoid huge ipv6 loop.
+	maxScanSubnets = 1 << 16
+)
+
+const (
+	RangeTypePod     = "pod"
+	RangeTypeService = "service"
+)
+
+type CIDRReport struct {
+	Overlaps      []CIDROverlap
+	Utilizations  []CIDRUtilization
+	Supernet      string
+	FreeRanges    []string
+	InvalidRanges []string
+}
+
+type CIDROverlap struct {
+	ClusterA   string
+	RangeTypeA string
+	CIDRA      string
+	ClusterB   string
+	RangeTypeB string
+	CIDRB      string
+}
+
+type CIDRUtilization struct {
+	ClusterName     string
+	PodCIDR         string
+	PodCIDRSize     float64
+	AllocatedPodIPs int32
+	Utilization     float64
+}
+
+type clusterRange struct {
+	clusterName string
+	rangeType   string
+	ipNet       *net.IPNet
+}
+
+// BuildCIDRReport check all cluster pod and service ranges, report overlap pairs,
+// pod ip utilization and free ranges in supernet.
+func BuildCIDRReport(list []resource.ClusterStatus, supernet string, prefixLen, count int) CIDRReport {
+	report := CIDRReport{
+		Overlaps:      []CIDROverlap{},
+		Utilizations:  []CIDRUtilization{},
+		Supernet:      supernet,
+		FreeRanges:    []string{},
+		InvalidRanges: []string{},
+	}
+
+	ranges := []clusterRange{}
+	for _, cs := range list {
+		podNets, invalid := parseCIDRs(cs.ClusterCIDR)
+		report.InvalidRanges = append(report.InvalidRanges, formatInvalid(cs.ClusterName, invalid)...)
+		serviceNets, invalid := parseCIDRs(cs.ServiceCIDR)
+		report.InvalidRanges = append(report.InvalidRanges, formatInvalid(cs.ClusterName, invalid)...)
+
+		// dual-stack pod get one ip per family, the smaller family is the limit
+		familySize := map[int]float64{}
+		for _, ipNet := range podNets {
+			ranges = append(ranges, clusterRange{clusterName: cs.ClusterName, rangeType: RangeTypePod, ipNet: ipNet})
+			familySize[len(ipNet.IP)] += cidrSize(ipNet)
+		}
+		var podCIDRSize float64
+		for _, size := range familySize {
+			if podCIDRSize == 0 || size < podCIDRSize {
+				podCIDRSize = size
+			}
+		}
+		for _, ipNet := range serviceNets {
+			ranges = append(ranges, clusterRange{clusterName: cs.ClusterName, rangeType: RangeTypeService, ipNet: ipNet})
+		}
+
+		utilization := CIDRUtilization{
+			ClusterName:     cs.ClusterName,
+			PodCIDR:         cs.ClusterCIDR,
+			PodCIDRSize:     podCIDRSize,
+			AllocatedPodIPs: cs.AllocatedPodIPs,
+		}
+		if podCIDRSize > 0 {
+			utilization.Utilization = float64(cs.AllocatedPodIPs) / podCIDRSize
+		}
+		report.Utilizations = append(report.Utilizations, utilization)
+	}
+
+	for i := 0; i < len(ranges); i++ {
+		for j := i + 1; j < len(ranges); j++ {
+			a, b := ranges[i], ranges[j]
+			if a.clusterName == b.clusterName {
+				continue
+			}
+			if !cidrOverlap(a.ipNet, b.ipNet) {
+				continue
+			}
+			report.Overlaps = append(report.Overlaps, CIDROverlap{
+				ClusterA:   a.clusterName,
+				RangeTypeA: a.rangeType,
+				CIDRA:      a.ipNet.String(),
+				ClusterB:   b.clusterName,
+				RangeTypeB: b.rangeType,
+				CIDRB:      b.ipNet.String(),
+			})
+		}
+	}
+
+	if supernet == "" {
+		return report
+	}
+	_, superNet, err := net.ParseCIDR(supernet)
+	if err != nil {
+		report.InvalidRanges = append(report.InvalidRanges, fmt.Sprintf("supernet %s", supernet))
+		return report
+	}
+	used := make([]*net.IPNet, 0, len(ranges))
+	for _, r := range ranges {
+		used = append(used, r.ipNet)
+	}
+	report.FreeRanges = suggestFreeRanges(superNet, used, prefixLen, count)
+	return report
+}
+
+// parseCIDRs parse single or dual-stack ranges such as 10.244.0.0/16,fd00:10:244::/56
+func parseCIDRs(s string) (nets []*net.IPNet, invalid []string) {
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			invalid = append(invalid, item)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return
+}
+
+func formatInvalid(clusterName string, invalid []string) []string {
+	list := make([]string, 0, len(invalid))
+	for _, item := range invalid {
+		list = append(list, fmt.Sprintf("%s %s", clusterName, item))
+	}
+	return list
+}
+
+func cidrOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func cidrSize(ipNet *net.IPNet) float64 {
+	ones, bits := ipNet.Mask.Size()
+	return math.Ldexp(1, bits-ones)
+}
+
+func suggestFreeRanges(supernet *net.IPNet, used []*net.IPNet, prefixLen, count int) []string {
+	free := []string{}
+	ones, bits := supernet.Mask.Size()
+	if prefixLen < ones || prefixLen > bits || count <= 0 {
+		return free
+	}
+
+	step := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+	total := new(big.Int).Lsh(big.NewInt(1), uint(prefixLen-ones))
+	if total.Cmp(big.NewInt(int64(maxScanSubnets))) > 0 {
+		total = big.NewInt(int64(maxScanSubnets))
+	}
+
+	ip := new(big.Int).SetBytes(supernet.IP)
+	mask := net.CIDRMask(prefixLen, bits)
+	for i := int64(0); i < total.Int64() && len(free) < count; i++ {
+		candidate := &net.IPNet{IP: bigIntToIP(ip, bits/8), Mask: mask}
+		overlap := false
+		for _, u := range used {
+			if cidrOverlap(candidate, u) {
+				overlap = true
+				break
+			}
+		}
+		if !overlap {
+			free = append(free, candidate.String())
+		}
+		ip.Add(ip, step)
+	}
+	return free
+}
+
+func bigIntToIP(i *big.Int, length int) net.IP {
+	b := i.Bytes()
+	ip := make(net.IP, length)
+	copy(ip[length-len(b):], b)
+	return ip
+}
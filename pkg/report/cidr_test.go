@@ -0,0 +1,85 @@
+package report
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+func TestSuggestFreeRanges(t *testing.T) {
+	parse := func(cidrs ...string) []*net.IPNet {
+		list := []*net.IPNet{}
+		for _, c := range cidrs {
+			_, ipNet, err := net.ParseCIDR(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list = append(list, ipNet)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name      string
+		supernet  string
+		used      []string
+		prefixLen int
+		count     int
+		expected  []string
+	}{
+		{"skip used", "10.0.0.0/8", []string{"10.0.0.0/16", "10.2.0.0/16"}, 16, 3, []string{"10.1.0.0/16", "10.3.0.0/16", "10.4.0.0/16"}},
+		{"smaller used range", "10.0.0.0/14", []string{"10.1.128.0/20"}, 16, 10, []string{"10.0.0.0/16", "10.2.0.0/16", "10.3.0.0/16"}},
+		{"larger used range", "10.0.0.0/14", []string{"10.0.0.0/15"}, 16, 10, []string{"10.2.0.0/16", "10.3.0.0/16"}},
+		{"full", "10.0.0.0/15", []string{"10.0.0.0/8"}, 16, 10, []string{}},
+		{"ipv6", "fd00::/48", []string{"fd00::/56"}, 56, 2, []string{"fd00:0:0:100::/56", "fd00:0:0:200::/56"}},
+		{"prefix shorter than supernet", "10.0.0.0/16", nil, 8, 10, []string{}},
+		{"zero count", "10.0.0.0/8", nil, 16, 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free := suggestFreeRanges(parse(tt.supernet)[0], parse(tt.used...), tt.prefixLen, tt.count)
+			if !reflect.DeepEqual(free, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, free)
+			}
+		})
+	}
+}
+
+func TestBuildCIDRReport(t *testing.T) {
+	list := []resource.ClusterStatus{
+		{ClusterName: "cluster-a", ClusterCIDR: "10.0.0.0/16,fd00:10::/112", ServiceCIDR: "10.96.0.0/12", AllocatedPodIPs: 1024},
+		{ClusterName: "cluster-b", ClusterCIDR: "10.0.128.0/17", ServiceCIDR: "10.100.0.0/16"},
+		{ClusterName: "cluster-c", ClusterCIDR: "bad-range", ServiceCIDR: ""},
+	}
+
+	report := BuildCIDRReport(list, "10.0.0.0/8", 16, 2)
+	expectedOverlaps := []CIDROverlap{
+		{ClusterA: "cluster-a", RangeTypeA: RangeTypePod, CIDRA: "10.0.0.0/16", ClusterB: "cluster-b", RangeTypeB: RangeTypePod, CIDRB: "10.0.128.0/17"},
+		{ClusterA: "cluster-a", RangeTypeA: RangeTypeService, CIDRA: "10.96.0.0/12", ClusterB: "cluster-b", RangeTypeB: RangeTypeService, CIDRB: "10.100.0.0/16"},
+	}
+	if !reflect.DeepEqual(report.Overlaps, expectedOverlaps) {
+		t.Errorf("unexpected overlaps %+v", report.Overlaps)
+	}
+	if !reflect.DeepEqual(report.InvalidRanges, []string{"cluster-c bad-range"}) {
+		t.Errorf("unexpected invalid ranges %v", report.InvalidRanges)
+	}
+	if !reflect.DeepEqual(report.FreeRanges, []string{"10.1.0.0/16", "10.2.0.0/16"}) {
+		t.Errorf("unexpected free ranges %v", report.FreeRanges)
+	}
+
+	// dual-stack pod ips are limited by the smaller family
+	a := report.Utilizations[0]
+	if a.PodCIDRSize != 65536 || a.Utilization != 1024.0/65536 {
+		t.Errorf("unexpected cluster-a utilization %+v", a)
+	}
+	if c := report.Utilizations[2]; c.PodCIDRSize != 0 || c.Utilization != 0 {
+		t.Errorf("unexpected cluster-c utilization %+v", c)
+	}
+
+	report = BuildCIDRReport(list, "not-a-cidr", 16, 2)
+	if len(report.FreeRanges) != 0 || report.InvalidRanges[len(report.InvalidRanges)-1] != "supernet not-a-cidr" {
+		t.Errorf("invalid supernet should be reported, got %+v", report)
+	}
+}
@@ -0,0 +1,268 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// PricingFile yaml or json file contains Pricing
+	PricingFile = ""
+	// GPUResourceNames resource names priced as gpu
+	GPUResourceNames = []string{"nvidia.com/gpu", "amd.com/gpu"}
+
+	hoursPerDay = 24.0
+	bytesPerGiB = float64(1 << 30)
+)
+
+// Price hourly price per vCPU, per GiB memory and per GPU
+type Price struct {
+	CPU       float64 `json:"cpu"`
+	MemoryGiB float64 `json:"memoryGiB"`
+	GPU       float64 `json:"gpu"`
+}
+
+// PriceRule node matched all NodeSelector labels use the price
+type PriceRule struct {
+	NodeSelector map[string]string `json:"nodeSelector"`
+	Price        Price             `json:"price"`
+}
+
+// Pricing node price resolve order: first matched NodeRules, Clusters, Default
+//
+//	default: {cpu: 0.03, memoryGiB: 0.004, gpu: 2.5}
+//	clusters:
+//	  cluster-a: {cpu: 0.02, memoryGiB: 0.003}
+//	nodeRules:
+//	- nodeSelector: {node.kubernetes.io/instance-type: p3.2xlarge}
+//	  price: {cpu: 0.05, memoryGiB: 0.005, gpu: 3.06}
+type Pricing struct {
+	Default   Price            `json:"default"`
+	Clusters  map[string]Price `json:"clusters"`
+	NodeRules []PriceRule      `json:"nodeRules"`
+}
+
+type CostReport struct {
+	Date       string
+	Clusters   []ClusterCost
+	Namespaces []NamespaceCost
+	Teams      []GroupCost
+	Apps       []GroupCost
+}
+
+type ClusterCost struct {
+	ClusterName   string
+	Cost          float64
+	RequestedCost float64
+	IdleCost      float64
+}
+
+type NamespaceCost struct {
+	ClusterName string
+	Namespace   string
+	Team        string
+	Cost        float64
+}
+
+type GroupCost struct {
+	Name string
+	Cost float64
+}
+
+// unitPrice average hourly price of one cluster, weighted by node allocatable
+type unitPrice struct {
+	cpu, memory, gpu float64
+}
+
+func LoadPricing(path string) (*Pricing, error) {
+	if path == "" {
+		return nil, fmt.Errorf("pricing file not configured")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing file %s failed: %+v", path, err)
+	}
+	pricing := &Pricing{}
+	if err = yaml.Unmarshal(data, pricing); err != nil {
+		return nil, fmt.Errorf("parse pricing file %s failed: %+v", path, err)
+	}
+	return pricing, nil
+}
+
+// BuildCostReport estimate daily spend of cluster, namespace, team and application,
+// namespace and application are priced by requests, idle cost is allocatable minus requested.
+func BuildCostReport(pricing *Pricing, statuses []resource.ClusterStatus, namespaces []resource.ClusterNamespaceStatus, summaries []resource.SummaryResourceUseage) CostReport {
+	report := CostReport{
+		Date:       time.Now().Format("2006-01-02"),
+		Clusters:   make([]ClusterCost, 0, len(statuses)),
+		Namespaces: []NamespaceCost{},
+		Teams:      []GroupCost{},
+		Apps:       []GroupCost{},
+	}
+
+	units := map[string]unitPrice{}
+	for _, cs := range statuses {
+		cc, unit := pricing.clusterCost(cs)
+		report.Clusters = append(report.Clusters, cc)
+		units[cs.ClusterName] = unit
+	}
+
+	teams := map[string]float64{}
+	for _, cns := range namespaces {
+		unit := units[cns.ClusterName]
+		for _, ns := range cns.Namespaces {
+			cost := unit.cost(ns.Requests)
+			report.Namespaces = append(report.Namespaces, NamespaceCost{
+				ClusterName: cns.ClusterName,
+				Namespace:   ns.Name,
+				Team:        ns.Team,
+				Cost:        cost,
+			})
+			team := ns.Team
+			if team == "" {
+				team = UnassignedTeam
+			}
+			teams[team] += cost
+		}
+	}
+	report.Teams = sortGroupCost(teams)
+
+	apps := map[string]float64{}
+	for _, sru := range summaries {
+		unit := units[sru.ClusterName]
+		for _, w := range flattenAppWorkloads(sru) {
+			if w.app == "" {
+				continue
+			}
+			apps[w.app] += unit.cost(multiplyResourceList(w.requests, int64(w.Replicas)))
+		}
+	}
+	report.Apps = sortGroupCost(apps)
+	return report
+}
+
+func (p *Pricing) clusterCost(cs resource.ClusterStatus) (ClusterCost, unitPrice) {
+	cc := ClusterCost{ClusterName: cs.ClusterName}
+	var cpu, memory, gpu float64
+	for _, node := range cs.Nodes {
+		price := p.nodePrice(cs.ClusterName, node.Labels)
+		cc.Cost += price.cost(node.Allocatable)
+		if nu, ok := cs.Utilization.Nodes[node.Name]; ok {
+			cc.RequestedCost += price.cost(nu.Requests)
+		}
+
+		c, m, g := resourceAmount(node.Allocatable)
+		cpu += c * price.CPU
+		memory += m * price.MemoryGiB
+		gpu += g * price.GPU
+	}
+	if idle := cc.Cost - cc.RequestedCost; idle > 0 {
+		cc.IdleCost = idle
+	}
+
+	// average unit price weighted by allocatable
+	unit := unitPrice{}
+	c, m, g := resourceAmount(cs.Allocatable)
+	if c > 0 {
+		unit.cpu = cpu / c
+	}
+	if m > 0 {
+		unit.memory = memory / m
+	}
+	if g > 0 {
+		unit.gpu = gpu / g
+	}
+	return cc, unit
+}
+
+func (p *Pricing) nodePrice(clusterName string, labels map[string]string) Price {
+	for _, rule := range p.NodeRules {
+		if matchLabels(rule.NodeSelector, labels) {
+			return rule.Price
+		}
+	}
+	if price, ok := p.Clusters[clusterName]; ok {
+		return price
+	}
+	return p.Default
+}
+
+// cost returns daily cost of resource list
+func (price Price) cost(list corev1.ResourceList) float64 {
+	cpu, memory, gpu := resourceAmount(list)
+	return (cpu*price.CPU + memory*price.MemoryGiB + gpu*price.GPU) * hoursPerDay
+}
+
+// cost returns daily cost of resource list
+func (unit unitPrice) cost(list corev1.ResourceList) float64 {
+	cpu, memory, gpu := resourceAmount(list)
+	return (cpu*unit.cpu + memory*unit.memory + gpu*unit.gpu) * hoursPerDay
+}
+
+// resourceAmount returns cpu in cores, memory in GiB and gpu count
+func resourceAmount(list corev1.ResourceList) (cpu, memory, gpu float64) {
+	cpu = list.Cpu().AsApproximateFloat64()
+	memory = list.Memory().AsApproximateFloat64() / bytesPerGiB
+	for _, name := range GPUResourceNames {
+		if q, ok := list[corev1.ResourceName(name)]; ok {
+			gpu += q.AsApproximateFloat64()
+		}
+	}
+	return
+}
+
+func matchLabels(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sortGroupCost(m map[string]float64) []GroupCost {
+	list := make([]GroupCost, 0, len(m))
+	for name, cost := range m {
+		list = append(list, GroupCost{Name: name, Cost: cost})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Cost > list[j].Cost
+	})
+	return list
+}
+
+// WriteCostCSV write cost report as csv with columns: date,scope,cluster,name,cost,idle
+func WriteCostCSV(w io.Writer, report CostReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "scope", "cluster", "name", "cost", "idle"})
+	for _, c := range report.Clusters {
+		cw.Write([]string{report.Date, "cluster", c.ClusterName, c.ClusterName, formatCost(c.Cost), formatCost(c.IdleCost)})
+	}
+	for _, ns := range report.Namespaces {
+		cw.Write([]string{report.Date, "namespace", ns.ClusterName, ns.Namespace, formatCost(ns.Cost), ""})
+	}
+	for _, t := range report.Teams {
+		cw.Write([]string{report.Date, "team", "", t.Name, formatCost(t.Cost), ""})
+	}
+	for _, a := range report.Apps {
+		cw.Write([]string{report.Date, "app", "", a.Name, formatCost(a.Cost), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCost(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestLoadPricing(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "pricing.yaml")
+	data := `
+default: {cpu: 0.04, memoryGiB: 0.005, gpu: 2}
+clusters:
+  cluster-b: {cpu: 0.02, memoryGiB: 0.002}
+nodeRules:
+- nodeSelector: {node.kubernetes.io/instance-type: gpu.large}
+  price: {cpu: 0.05, memoryGiB: 0.01, gpu: 3}
+`
+	if err := ioutil.WriteFile(valid, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := ioutil.WriteFile(invalid, []byte("default: [cpu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pricing, err := LoadPricing(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pricing.Default.CPU != 0.04 || pricing.Default.GPU != 2 || pricing.Clusters["cluster-b"].MemoryGiB != 0.002 ||
+		len(pricing.NodeRules) != 1 || pricing.NodeRules[0].Price.GPU != 3 {
+		t.Errorf("unexpected pricing %+v", pricing)
+	}
+
+	for _, path := range []string{"", filepath.Join(dir, "missing.yaml"), invalid} {
+		if _, err = LoadPricing(path); err == nil {
+			t.Errorf("expected error loading pricing %q", path)
+		}
+	}
+}
+
+func TestBuildCostReport(t *testing.T) {
+	list := func(pairs ...string) corev1.ResourceList {
+		rl := corev1.ResourceList{}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rl[corev1.ResourceName(pairs[i])] = apiresource.MustParse(pairs[i+1])
+		}
+		return rl
+	}
+	pricing := &Pricing{
+		Default:  Price{CPU: 0.04, MemoryGiB: 0.005, GPU: 2},
+		Clusters: map[string]Price{"cluster-b": {CPU: 0.02, MemoryGiB: 0.002}},
+		NodeRules: []PriceRule{
+			{NodeSelector: map[string]string{"node.kubernetes.io/instance-type": "gpu.large"}, Price: Price{CPU: 0.05, MemoryGiB: 0.01, GPU: 3}},
+		},
+	}
+	statuses := []resource.ClusterStatus{
+		{
+			ClusterName: "cluster-a",
+			Allocatable: list("cpu", "12", "memory", "48Gi", "nvidia.com/gpu", "2"),
+			Nodes: []resource.NodeInfo{
+				{Name: "node-a", Allocatable: list("cpu", "4", "memory", "16Gi")},
+				{Name: "node-g", Labels: map[string]string{"node.kubernetes.io/instance-type": "gpu.large"}, Allocatable: list("cpu", "8", "memory", "32Gi", "nvidia.com/gpu", "2")},
+			},
+			Utilization: resource.ClusterUtilization{Nodes: map[string]resource.ResourceUtilization{
+				"node-a": {Requests: list("cpu", "2", "memory", "4Gi")},
+				"node-g": {Requests: list("cpu", "4", "memory", "8Gi", "nvidia.com/gpu", "1")},
+			}},
+		},
+		{
+			// gpu has no price in cluster-b
+			ClusterName: "cluster-b",
+			Allocatable: list("cpu", "2", "memory", "4Gi", "nvidia.com/gpu", "1"),
+			Nodes:       []resource.NodeInfo{{Name: "node-b", Allocatable: list("cpu", "2", "memory", "4Gi", "nvidia.com/gpu", "1")}},
+		},
+	}
+	namespaces := []resource.ClusterNamespaceStatus{
+		{ClusterName: "cluster-a", Namespaces: []resource.NamespaceStatus{{Name: "ns-a", Team: "payments", Requests: list("cpu", "1", "memory", "1Gi", "nvidia.com/gpu", "1")}}},
+		{ClusterName: "cluster-b", Namespaces: []resource.NamespaceStatus{{Name: "ns-b", Requests: list("cpu", "1", "memory", "2Gi", "nvidia.com/gpu", "1")}}},
+	}
+	summaries := []resource.SummaryResourceUseage{
+		{ClusterName: "cluster-a", DeploymentStatistics: resource.DeploymentStatistics{List: map[string][]resource.DeploymentStatus{
+			"ns-a": {{Name: "web", ShowName: "web", Replicas: 2, Resource: resource.Resouces{Requests: list("cpu", "500m", "memory", "1Gi")}}},
+		}}},
+	}
+
+	report := BuildCostReport(pricing, statuses, namespaces, summaries)
+
+	near := func(name string, got, expected float64) {
+		if math.Abs(got-expected) > 1e-6 {
+			t.Errorf("%s: expected %.6f, got %.6f", name, expected, got)
+		}
+	}
+	// node-a (0.04*4 + 0.005*16) * 24 = 5.76, node-g (0.05*8 + 0.01*32 + 3*2) * 24 = 161.28
+	near("cluster-a cost", report.Clusters[0].Cost, 167.04)
+	// node-a (0.04*2 + 0.005*4) * 24 = 2.4, node-g (0.05*4 + 0.01*8 + 3*1) * 24 = 78.72
+	near("cluster-a requested", report.Clusters[0].RequestedCost, 81.12)
+	near("cluster-a idle", report.Clusters[0].IdleCost, 85.92)
+	// missing gpu price costs nothing: (0.02*2 + 0.002*4) * 24
+	near("cluster-b cost", report.Clusters[1].Cost, 1.152)
+
+	// unit price of cluster-a weighted by allocatable: cpu 0.56/12, memory 0.4/48, gpu 6/2
+	near("ns-a cost", report.Namespaces[0].Cost, (0.56/12+0.4/48+3)*24)
+	near("ns-b cost", report.Namespaces[1].Cost, (0.02+0.002*2)*24)
+	if len(report.Teams) != 2 || report.Teams[0].Name != "payments" || report.Teams[1].Name != UnassignedTeam {
+		t.Errorf("unexpected teams %+v", report.Teams)
+	}
+	if len(report.Apps) != 1 || report.Apps[0].Name != "web" {
+		t.Fatalf("unexpected apps %+v", report.Apps)
+	}
+	near("web cost", report.Apps[0].Cost, (0.56/12*0.5+0.4/48)*2*24)
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+type CRDPresence struct {
+	Name  string
+	Group string
+	// Clusters cluster -> served versions
+	Clusters map[string][]string
+	// StorageVersions cluster -> storage version
+	StorageVersions map[string]string
+	MissingClusters []string
+	// UnknownClusters CRDs not collected, excluded from MissingClusters
+	UnknownClusters []string
+}
+
+// BuildCRDReport show which clusters install or lack every CRD in the fleet,
+// name and group filter CRDs when not empty, group matches all CRDs of an operator.
+func BuildCRDReport(list []resource.APIInventory, name, group string) []CRDPresence {
+	crds := map[string]*CRDPresence{}
+	for _, inv := range list {
+		for _, crd := range inv.CRDs {
+			if name != "" && crd.Name != name || group != "" && crd.Group != group {
+				continue
+			}
+			p, ok := crds[crd.Name]
+			if !ok {
+				p = &CRDPresence{
+					Name:            crd.Name,
+					Group:           crd.Group,
+					Clusters:        map[string][]string{},
+					StorageVersions: map[string]string{},
+				}
+				crds[crd.Name] = p
+			}
+			served := []string{}
+			for _, v := range crd.Versions {
+				if v.Served {
+					served = append(served, v.Name)
+				}
+			}
+			p.Clusters[inv.ClusterName] = served
+			p.StorageVersions[inv.ClusterName] = crd.StorageVersion
+		}
+	}
+
+	// requested crd isn't installed anywhere, every cluster lacks it
+	if name != "" && len(crds) == 0 {
+		crds[name] = &CRDPresence{
+			Name:            name,
+			Group:           group,
+			Clusters:        map[string][]string{},
+			StorageVersions: map[string]string{},
+		}
+	}
+
+	result := make([]CRDPresence, 0, len(crds))
+	for _, p := range crds {
+		p.MissingClusters = []string{}
+		p.UnknownClusters = []string{}
+		for _, inv := range list {
+			if !inv.CRDsCollected {
+				p.UnknownClusters = append(p.UnknownClusters, inv.ClusterName)
+				continue
+			}
+			if _, ok := p.Clusters[inv.ClusterName]; !ok {
+				p.MissingClusters = append(p.MissingClusters, inv.ClusterName)
+			}
+		}
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+func TestBuildCRDReport(t *testing.T) {
+	crd := func(name, group, storage string, served ...string) resource.CRDInfo {
+		info := resource.CRDInfo{Name: name, Group: group, StorageVersion: storage}
+		for _, v := range served {
+			info.Versions = append(info.Versions, resource.CRDVersion{Name: v, Served: true, Storage: v == storage})
+		}
+		return info
+	}
+	list := []resource.APIInventory{
+		{ClusterName: "cluster-a", CRDsCollected: true, CRDs: []resource.CRDInfo{
+			crd("certificates.cert-manager.io", "cert-manager.io", "v1", "v1"),
+			crd("issuers.cert-manager.io", "cert-manager.io", "v1", "v1"),
+		}},
+		{ClusterName: "cluster-b", CRDsCollected: true, CRDs: []resource.CRDInfo{
+			crd("certificates.cert-manager.io", "cert-manager.io", "v1alpha2", "v1alpha2", "v1"),
+		}},
+		{ClusterName: "cluster-c", CRDsCollected: true, CRDs: []resource.CRDInfo{}},
+		// list crds failed, must not be reported as missing
+		{ClusterName: "cluster-d", CRDs: []resource.CRDInfo{}},
+	}
+
+	result := BuildCRDReport(list, "", "cert-manager.io")
+	if len(result) != 2 {
+		t.Fatalf("expected 2 crds, got %d", len(result))
+	}
+	cert := result[0]
+	if cert.Name != "certificates.cert-manager.io" {
+		t.Fatalf("unexpected order %s", cert.Name)
+	}
+	if !reflect.DeepEqual(cert.MissingClusters, []string{"cluster-c"}) || !reflect.DeepEqual(cert.UnknownClusters, []string{"cluster-d"}) {
+		t.Errorf("certificates missing %v unknown %v", cert.MissingClusters, cert.UnknownClusters)
+	}
+	if cert.StorageVersions["cluster-b"] != "v1alpha2" || !reflect.DeepEqual(cert.Clusters["cluster-b"], []string{"v1alpha2", "v1"}) {
+		t.Errorf("unexpected cluster-b versions %v %v", cert.Clusters["cluster-b"], cert.StorageVersions["cluster-b"])
+	}
+	if !reflect.DeepEqual(result[1].MissingClusters, []string{"cluster-b", "cluster-c"}) {
+		t.Errorf("issuers missing %v", result[1].MissingClusters)
+	}
+
+	absent := BuildCRDReport(list, "foos.example.com", "")
+	if len(absent) != 1 || !reflect.DeepEqual(absent[0].MissingClusters, []string{"cluster-a", "cluster-b", "cluster-c"}) {
+		t.Errorf("absent crd %+v", absent)
+	}
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+var defaultRegistry = "docker.io"
+
+type ImageUsage struct {
+	ClusterName string
+	resource.ImageReference
+}
+
+// ImageView one image repository and tag across the fleet
+type ImageView struct {
+	Repository string
+	Tag        string
+	Digests    []string
+	Clusters   []string
+	Workloads  int
+	Pods       int32
+}
+
+// ImageIndex queryable image usages of all clusters, repositories are normalized
+// so nginx, docker.io/nginx and docker.io/library/nginx are the same image.
+type ImageIndex struct {
+	usages       []ImageUsage
+	byRepository map[string][]int
+}
+
+func BuildImageIndex(list []resource.ClusterImageInventory) *ImageIndex {
+	index := &ImageIndex{
+		usages:       []ImageUsage{},
+		byRepository: map[string][]int{},
+	}
+	for _, inv := range list {
+		for _, ref := range inv.Images {
+			repository := NormalizeRepository(ref.Repository)
+			index.byRepository[repository] = append(index.byRepository[repository], len(index.usages))
+			index.usages = append(index.usages, ImageUsage{ClusterName: inv.ClusterName, ImageReference: ref})
+		}
+	}
+	return index
+}
+
+// Usages all image usages
+func (index *ImageIndex) Usages() []ImageUsage {
+	return index.usages
+}
+
+// Find usages of image, image is repository, repository:tag or repository@digest
+func (index *ImageIndex) Find(image string) []ImageUsage {
+	repository, tag, digest := resource.ParseImageReference(image)
+	result := []ImageUsage{}
+	for _, i := range index.byRepository[NormalizeRepository(repository)] {
+		u := index.usages[i]
+		if tag != "" && u.Tag != tag || digest != "" && u.Digest != digest {
+			continue
+		}
+		result = append(result, u)
+	}
+	return result
+}
+
+// Clusters names of clusters running image
+func (index *ImageIndex) Clusters(image string) []string {
+	clusters := []string{}
+	for _, u := range index.Find(image) {
+		if !containsString(clusters, u.ClusterName) {
+			clusters = append(clusters, u.ClusterName)
+		}
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// IsMutableImage image uses :latest or is untagged
+func IsMutableImage(u ImageUsage) bool {
+	return u.Latest || u.Untagged
+}
+
+// Views group usages by normalized repository and tag
+func (index *ImageIndex) Views() []ImageView {
+	views := map[string]*ImageView{}
+	for repository, list := range index.byRepository {
+		for _, i := range list {
+			u := index.usages[i]
+			key := repository + ":" + u.Tag
+			view, ok := views[key]
+			if !ok {
+				view = &ImageView{Repository: repository, Tag: u.Tag, Digests: []string{}, Clusters: []string{}}
+				views[key] = view
+			}
+			if u.Digest != "" && !containsString(view.Digests, u.Digest) {
+				view.Digests = append(view.Digests, u.Digest)
+			}
+			if !containsString(view.Clusters, u.ClusterName) {
+				view.Clusters = append(view.Clusters, u.ClusterName)
+			}
+			view.Workloads++
+			view.Pods += u.Pods
+		}
+	}
+
+	result := make([]ImageView, 0, len(views))
+	for _, view := range views {
+		sort.Strings(view.Digests)
+		sort.Strings(view.Clusters)
+		result = append(result, *view)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Repository != result[j].Repository {
+			return result[i].Repository < result[j].Repository
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
+
+// NormalizeRepository complete default registry and library namespace of docker hub images
+func NormalizeRepository(repository string) string {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) == 1 {
+		return defaultRegistry + "/library/" + repository
+	}
+	// first part is registry when it contains . or : or is localhost
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return defaultRegistry + "/" + repository
+	}
+	if parts[0] == "index.docker.io" || parts[0] == "registry-1.docker.io" {
+		return NormalizeRepository(parts[1])
+	}
+	if parts[0] == defaultRegistry && !strings.Contains(parts[1], "/") {
+		return defaultRegistry + "/library/" + parts[1]
+	}
+	return repository
+}
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// RBACBaselineFile yaml or json file contains RBACBaseline
+var RBACBaselineFile = ""
+
+// RBACBaseline risky grants allowed in the fleet, subject supports path.Match patterns
+//
+//	allowed:
+//	- subject: Group:platform-admins
+//	  risks: [cluster-admin]
+//	- subject: ServiceAccount:argocd/*
+//	  clusters: [cluster-a]
+type RBACBaseline struct {
+	Allowed []RBACAllowed `json:"allowed"`
+}
+
+type RBACAllowed struct {
+	Subject string `json:"subject"`
+	// Risks empty allows all risks
+	Risks []string `json:"risks"`
+	// Clusters empty applies to all clusters
+	Clusters []string `json:"clusters"`
+}
+
+type RBACDiff struct {
+	ClusterName string
+	// Unexpected risks not allowed by baseline
+	Unexpected []resource.SubjectExposure
+	// Unused baseline subjects matched no subject in the cluster
+	Unused []string
+}
+
+func LoadRBACBaseline(file string) (*RBACBaseline, error) {
+	if file == "" {
+		return nil, fmt.Errorf("rbac baseline file not configured")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read rbac baseline file %s failed: %+v", file, err)
+	}
+	baseline := &RBACBaseline{}
+	if err = yaml.Unmarshal(data, baseline); err != nil {
+		return nil, fmt.Errorf("parse rbac baseline file %s failed: %+v", file, err)
+	}
+	return baseline, nil
+}
+
+// BuildRBACDiff compare risky subjects of every cluster against baseline
+func BuildRBACDiff(baseline *RBACBaseline, list []resource.ClusterRBACExposure) []RBACDiff {
+	result := make([]RBACDiff, 0, len(list))
+	for _, exposure := range list {
+		diff := RBACDiff{
+			ClusterName: exposure.ClusterName,
+			Unexpected:  []resource.SubjectExposure{},
+			Unused:      []string{},
+		}
+		used := map[int]bool{}
+		for _, se := range exposure.Subjects {
+			unexpected := []string{}
+			for _, risk := range se.Risks {
+				if i := baseline.allow(exposure.ClusterName, se.Subject, risk); i >= 0 {
+					used[i] = true
+					continue
+				}
+				unexpected = append(unexpected, risk)
+			}
+			if len(unexpected) > 0 {
+				diff.Unexpected = append(diff.Unexpected, resource.SubjectExposure{
+					Subject:  se.Subject,
+					Risks:    unexpected,
+					Bindings: se.Bindings,
+				})
+			}
+		}
+		for i, allowed := range baseline.Allowed {
+			if !used[i] && allowed.appliesTo(exposure.ClusterName) {
+				diff.Unused = append(diff.Unused, allowed.Subject)
+			}
+		}
+		sort.Strings(diff.Unused)
+		result = append(result, diff)
+	}
+	return result
+}
+
+// allow returns index of the first allowed entry matches, -1 means not allowed
+func (b *RBACBaseline) allow(clusterName, subject, risk string) int {
+	for i, allowed := range b.Allowed {
+		if !allowed.appliesTo(clusterName) {
+			continue
+		}
+		if matched, err := path.Match(allowed.Subject, subject); err != nil || !matched {
+			continue
+		}
+		if len(allowed.Risks) == 0 || containsString(allowed.Risks, risk) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a RBACAllowed) appliesTo(clusterName string) bool {
+	return len(a.Clusters) == 0 || containsString(a.Clusters, clusterName)
+}
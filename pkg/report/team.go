@@ -0,0 +1,77 @@
+package report
+
+import (
+	"sort"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// UnassignedTeam group namespaces without team label
+var UnassignedTeam = "unassigned"
+
+type TeamView struct {
+	Team          string
+	Clusters      []string
+	Namespaces    []string
+	WorkloadCount map[string]int
+	Pods          int32
+	Requests      corev1.ResourceList
+	Limits        corev1.ResourceList
+	QuotaHard     corev1.ResourceList
+	QuotaUsed     corev1.ResourceList
+}
+
+// BuildTeamReport rollup namespaces of all clusters by team label,
+// namespace is shown as cluster/namespace.
+func BuildTeamReport(list []resource.ClusterNamespaceStatus) []TeamView {
+	teams := map[string]*TeamView{}
+	for _, cns := range list {
+		for _, ns := range cns.Namespaces {
+			name := ns.Team
+			if name == "" {
+				name = UnassignedTeam
+			}
+			team, ok := teams[name]
+			if !ok {
+				team = &TeamView{
+					Team:          name,
+					Clusters:      []string{},
+					Namespaces:    []string{},
+					WorkloadCount: map[string]int{},
+					Requests:      corev1.ResourceList{},
+					Limits:        corev1.ResourceList{},
+					QuotaHard:     corev1.ResourceList{},
+					QuotaUsed:     corev1.ResourceList{},
+				}
+				teams[name] = team
+			}
+
+			if !containsString(team.Clusters, cns.ClusterName) {
+				team.Clusters = append(team.Clusters, cns.ClusterName)
+			}
+			team.Namespaces = append(team.Namespaces, cns.ClusterName+"/"+ns.Name)
+			for kind, n := range ns.WorkloadCount {
+				team.WorkloadCount[kind] += n
+			}
+			team.Pods += ns.Pods
+			resource.AddResourceList(team.Requests, ns.Requests)
+			resource.AddResourceList(team.Limits, ns.Limits)
+			for _, quota := range ns.ResourceQuotas {
+				resource.AddResourceList(team.QuotaHard, quota.Hard)
+				resource.AddResourceList(team.QuotaUsed, quota.Used)
+			}
+		}
+	}
+
+	result := make([]TeamView, 0, len(teams))
+	for _, team := range teams {
+		sort.Strings(team.Clusters)
+		sort.Strings(team.Namespaces)
+		result = append(result, *team)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Team < result[j].Team
+	})
+	return result
+}
@@ -0,0 +1,149 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+var (
+	// EOLBeforeVersion minor versions older than this are end of life, empty means the oldest of SupportedMinorVersions up to target
+	EOLBeforeVersion = ""
+	// SupportedMinorVersions number of minor versions supported upstream
+	SupportedMinorVersions uint = 3
+	// MaxKubeletSkew kubelet may be older than apiserver up to this many minor versions
+	MaxKubeletSkew uint = 2
+)
+
+type UpgradeReport struct {
+	TargetVersion string
+	EOLBefore     string
+	Clusters      []ClusterUpgrade
+	// EOLClusters clusters running end of life minor version
+	EOLClusters []string
+}
+
+type ClusterUpgrade struct {
+	ClusterName       string
+	KubernetesVersion string
+	// MinorsBehind minor versions between apiserver and target
+	MinorsBehind        int
+	EOL                 bool
+	SkewViolations      []KubeletSkew
+	DeprecatedAPIUsages []resource.DeprecatedAPIUsage
+	Ready               bool
+	Reasons             []string
+}
+
+type KubeletSkew struct {
+	NodeName       string
+	KubeletVersion string
+	Reason         string
+}
+
+// BuildUpgradeReport compare every cluster to target version, flag kubelet skew beyond supported policy,
+// end of life minor versions and objects using apis removed in target version.
+func BuildUpgradeReport(target string, list []resource.ClusterStatus) (UpgradeReport, error) {
+	targetVersion, err := version.ParseGeneric(target)
+	if err != nil {
+		return UpgradeReport{}, fmt.Errorf("parse target version %s failed: %+v", target, err)
+	}
+	eolBefore, err := eolBeforeVersion(targetVersion)
+	if err != nil {
+		return UpgradeReport{}, err
+	}
+
+	report := UpgradeReport{
+		TargetVersion: target,
+		EOLBefore:     fmt.Sprintf("v%d.%d", eolBefore.Major(), eolBefore.Minor()),
+		Clusters:      make([]ClusterUpgrade, 0, len(list)),
+		EOLClusters:   []string{},
+	}
+	for _, cs := range list {
+		cu := ClusterUpgrade{
+			ClusterName:         cs.ClusterName,
+			KubernetesVersion:   cs.KubernetesVersion,
+			SkewViolations:      []KubeletSkew{},
+			DeprecatedAPIUsages: []resource.DeprecatedAPIUsage{},
+			Reasons:             []string{},
+		}
+		// usages of all RemovedAPIs are collected, keep those removed in or before this target
+		for _, usage := range cs.DeprecatedAPIUsages {
+			if removedIn, err := version.ParseGeneric(usage.RemovedIn); err == nil && !targetVersion.LessThan(removedIn) {
+				cu.DeprecatedAPIUsages = append(cu.DeprecatedAPIUsages, usage)
+			}
+		}
+
+		apiserver, err := version.ParseGeneric(cs.KubernetesVersion)
+		if err != nil {
+			cu.Reasons = append(cu.Reasons, fmt.Sprintf("unknown kubernetes version %q", cs.KubernetesVersion))
+			report.Clusters = append(report.Clusters, cu)
+			continue
+		}
+		cu.MinorsBehind = int(targetVersion.Minor()) - int(apiserver.Minor())
+		if cu.MinorsBehind > 1 {
+			cu.Reasons = append(cu.Reasons, fmt.Sprintf("%d minor versions behind target, control plane must upgrade one minor at a time", cu.MinorsBehind))
+		}
+		if apiserver.LessThan(eolBefore) {
+			cu.EOL = true
+			cu.Reasons = append(cu.Reasons, fmt.Sprintf("minor version v%d.%d is end of life", apiserver.Major(), apiserver.Minor()))
+			report.EOLClusters = append(report.EOLClusters, cs.ClusterName)
+		}
+
+		for _, node := range cs.Nodes {
+			if reason := kubeletSkew(apiserver, targetVersion, node.KubeletVersion); reason != "" {
+				cu.SkewViolations = append(cu.SkewViolations, KubeletSkew{
+					NodeName:       node.Name,
+					KubeletVersion: node.KubeletVersion,
+					Reason:         reason,
+				})
+			}
+		}
+		if len(cu.SkewViolations) > 0 {
+			cu.Reasons = append(cu.Reasons, fmt.Sprintf("%d nodes violate kubelet version skew policy", len(cu.SkewViolations)))
+		}
+		if len(cu.DeprecatedAPIUsages) > 0 {
+			cu.Reasons = append(cu.Reasons, fmt.Sprintf("%d objects use apis removed in %s", len(cu.DeprecatedAPIUsages), target))
+		}
+
+		// more than one minor behind or end of life can't upgrade to target directly
+		cu.Ready = len(cu.SkewViolations) == 0 && len(cu.DeprecatedAPIUsages) == 0 && cu.MinorsBehind <= 1 && !cu.EOL
+		report.Clusters = append(report.Clusters, cu)
+	}
+	return report, nil
+}
+
+func eolBeforeVersion(target *version.Version) (*version.Version, error) {
+	if EOLBeforeVersion != "" {
+		v, err := version.ParseGeneric(EOLBeforeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("parse eol version %s failed: %+v", EOLBeforeVersion, err)
+		}
+		return v, nil
+	}
+	minor := uint(0)
+	if target.Minor() >= SupportedMinorVersions-1 {
+		minor = target.Minor() - (SupportedMinorVersions - 1)
+	}
+	return version.ParseGeneric(fmt.Sprintf("%d.%d", target.Major(), minor))
+}
+
+// kubeletSkew kubelet must not be newer than apiserver, and must not be older than MaxKubeletSkew minors
+// behind both current apiserver and target, otherwise it blocks upgrade.
+func kubeletSkew(apiserver, target *version.Version, kubeletVersion string) string {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return fmt.Sprintf("unknown kubelet version %q", kubeletVersion)
+	}
+	if kubelet.Minor() > apiserver.Minor() {
+		return fmt.Sprintf("kubelet is newer than apiserver v%d.%d", apiserver.Major(), apiserver.Minor())
+	}
+	if apiserver.Minor()-kubelet.Minor() > MaxKubeletSkew {
+		return fmt.Sprintf("kubelet is more than %d minor versions older than apiserver", MaxKubeletSkew)
+	}
+	if target.Minor() > kubelet.Minor() && target.Minor()-kubelet.Minor() > MaxKubeletSkew {
+		return fmt.Sprintf("kubelet is more than %d minor versions older than target, upgrade nodes first", MaxKubeletSkew)
+	}
+	return ""
+}
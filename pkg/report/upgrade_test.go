@@ -0,0 +1,99 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/champly/clustermanager/pkg/collect/resource"
+)
+
+func TestBuildUpgradeReport(t *testing.T) {
+	nodes := func(versions ...string) []resource.NodeInfo {
+		list := []resource.NodeInfo{}
+		for i, v := range versions {
+			list = append(list, resource.NodeInfo{Name: "node-" + string(rune('a'+i)), KubeletVersion: v})
+		}
+		return list
+	}
+	list := []resource.ClusterStatus{
+		{
+			ClusterName:       "cluster-a",
+			KubernetesVersion: "v1.24.3",
+			Nodes:             nodes("v1.24.3", "v1.22.1"),
+			DeprecatedAPIUsages: []resource.DeprecatedAPIUsage{
+				{APIVersion: "policy/v1beta1", Resource: "poddisruptionbudgets", Name: "web", RemovedIn: "v1.25"},
+				{APIVersion: "autoscaling/v2beta2", Resource: "horizontalpodautoscalers", Name: "web", RemovedIn: "v1.26"},
+			},
+		},
+		{ClusterName: "cluster-b", KubernetesVersion: "v1.22.0", Nodes: nodes("v1.23.1")},
+		{ClusterName: "cluster-c", KubernetesVersion: "v1.25.0-eks", Nodes: nodes("v1.25.0", "v1.23.5")},
+		{ClusterName: "cluster-d", KubernetesVersion: ""},
+		{ClusterName: "cluster-e", KubernetesVersion: "v1.23.4", Nodes: nodes("v1.23.4")},
+	}
+
+	report, err := BuildUpgradeReport("v1.25.0", list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.EOLBefore != "v1.23" || !reflect.DeepEqual(report.EOLClusters, []string{"cluster-b"}) {
+		t.Errorf("unexpected eol before %s clusters %v", report.EOLBefore, report.EOLClusters)
+	}
+
+	tests := []struct {
+		clusterName  string
+		minorsBehind int
+		eol          bool
+		skew         []string
+		usages       int
+		ready        bool
+		reason       string
+	}{
+		{"cluster-a", 1, false, []string{"node-b"}, 1, false, "removed in v1.25.0"},
+		{"cluster-b", 3, true, []string{"node-a"}, 0, false, "kubelet version skew"},
+		{"cluster-c", 0, false, []string{}, 0, true, ""},
+		{"cluster-d", 0, false, []string{}, 0, false, "unknown kubernetes version"},
+		{"cluster-e", 2, false, []string{}, 0, false, "2 minor versions behind target"},
+	}
+	for i, tt := range tests {
+		cu := report.Clusters[i]
+		if cu.ClusterName != tt.clusterName {
+			t.Fatalf("unexpected order %s", cu.ClusterName)
+		}
+		skew := []string{}
+		for _, s := range cu.SkewViolations {
+			skew = append(skew, s.NodeName)
+		}
+		if cu.MinorsBehind != tt.minorsBehind || cu.EOL != tt.eol || !reflect.DeepEqual(skew, tt.skew) ||
+			len(cu.DeprecatedAPIUsages) != tt.usages || cu.Ready != tt.ready {
+			t.Errorf("%s unexpected %+v", tt.clusterName, cu)
+		}
+		if !strings.Contains(strings.Join(cu.Reasons, "; "), tt.reason) {
+			t.Errorf("%s expected reason contains %q, got %v", tt.clusterName, tt.reason, cu.Reasons)
+		}
+	}
+
+	if reason := report.Clusters[1].SkewViolations[0].Reason; !strings.Contains(reason, "newer than apiserver") {
+		t.Errorf("unexpected cluster-b skew reason %q", reason)
+	}
+	if reason := report.Clusters[0].SkewViolations[0].Reason; !strings.Contains(reason, "older than target") {
+		t.Errorf("unexpected cluster-a skew reason %q", reason)
+	}
+
+	// end of life alone blocks upgrade
+	EOLBeforeVersion = "v1.25"
+	defer func() { EOLBeforeVersion = "" }()
+	report, err = BuildUpgradeReport("v1.25.0", []resource.ClusterStatus{
+		{ClusterName: "cluster-f", KubernetesVersion: "v1.24.1", Nodes: nodes("v1.24.1")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cu := report.Clusters[0]; !cu.EOL || cu.MinorsBehind != 1 || cu.Ready {
+		t.Errorf("cluster-f unexpected %+v", cu)
+	}
+
+	if _, err = BuildUpgradeReport("latest", list); err == nil {
+		t.Error("expected error for invalid target version")
+	}
+}
@@ -0,0 +1,466 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/champly/clustermanager/pkg/advisory"
+	"github.com/champly/clustermanager/pkg/collect/resource"
+	"github.com/champly/clustermanager/pkg/report"
+	"github.com/symcn/pkg/metrics"
+	"k8s.io/klog/v2"
+)
+
+var (
+	ListenAddr      = ":8080"
+	shutdownTimeout = time.Second * 5
+)
+
+type Server struct {
+	ctx context.Context
+	mux *http.ServeMux
+}
+
+func New(ctx context.Context) *Server {
+	s := &Server{
+		ctx: ctx,
+		mux: http.NewServeMux(),
+	}
+	s.registryRoute()
+	return s
+}
+
+func (s *Server) registryRoute() {
+	s.mux.HandleFunc("/api/v1/clusters", s.listClusterStatus)
+	s.mux.HandleFunc("/api/v1/health", s.listClusterHealth)
+	s.mux.HandleFunc("/api/v1/nodes", s.listNodes)
+	s.mux.HandleFunc("/api/v1/controlplane", s.listControlPlane)
+	s.mux.HandleFunc("/api/v1/workloads", s.listWorkloads)
+	s.mux.HandleFunc("/api/v1/namespaces", s.listNamespaces)
+	s.mux.HandleFunc("/api/v1/apps", s.listApps)
+	s.mux.HandleFunc("/api/v1/apiresources", s.listAPIInventory)
+	s.mux.HandleFunc("/api/v1/images", s.listImages)
+	s.mux.HandleFunc("/api/v1/vulnerabilities", s.listVulnerabilities)
+	s.mux.HandleFunc("/api/v1/security", s.listSecurityPosture)
+	s.mux.HandleFunc("/api/v1/rbac", s.listRBACExposure)
+	s.mux.HandleFunc("/api/v1/certificates", s.listCertificates)
+	s.mux.HandleFunc("/api/v1/reports/cidr", s.cidrReport)
+	s.mux.HandleFunc("/api/v1/reports/capacity", s.capacityReport)
+	s.mux.HandleFunc("/api/v1/reports/teams", s.teamReport)
+	s.mux.HandleFunc("/api/v1/reports/cost", s.costReport)
+	s.mux.HandleFunc("/api/v1/reports/upgrade", s.upgradeReport)
+	s.mux.HandleFunc("/api/v1/reports/crds", s.crdReport)
+	s.mux.HandleFunc("/api/v1/reports/images", s.imageReport)
+	s.mux.HandleFunc("/api/v1/reports/rbac", s.rbacReport)
+	metrics.RegisterHTTPHandler(s.mux.Handle)
+}
+
+// Start start http server and blocks until the context is cancelled
+func (s *Server) Start() error {
+	srv := &http.Server{
+		Addr:    ListenAddr,
+		Handler: s.mux,
+	}
+
+	go func() {
+		<-s.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			klog.Errorf("Shutdown api server failed: %+v", err)
+		}
+	}()
+
+	klog.Infof("Start api server on %s", ListenAddr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("api server listen on %s failed: %+v", ListenAddr, err)
+	}
+	return nil
+}
+
+func (s *Server) listClusterStatus(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		clusterStatus, ok := resource.GetCacheClusterStatusWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, clusterStatus)
+		return
+	}
+
+	list := resource.GetAllCacheClusterStatus()
+	distribution := r.URL.Query().Get("distribution")
+	provider := r.URL.Query().Get("provider")
+	if distribution == "" && provider == "" {
+		writeJSON(w, list)
+		return
+	}
+
+	filtered := []resource.ClusterStatus{}
+	for _, clusterStatus := range list {
+		if distribution != "" && clusterStatus.Distribution.Distribution != distribution {
+			continue
+		}
+		if provider != "" && clusterStatus.Distribution.CloudProvider != provider {
+			continue
+		}
+		filtered = append(filtered, clusterStatus)
+	}
+	writeJSON(w, filtered)
+}
+
+func (s *Server) listClusterHealth(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		health, ok := resource.GetCacheClusterHealthWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, health)
+		return
+	}
+	writeJSON(w, resource.GetAllCacheClusterHealth())
+}
+
+// listNodes return node inventory group by cluster name,
+// kubeletVersion query param return nodes not running the version, used for find stragglers.
+func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
+	clusterName := r.URL.Query().Get("cluster")
+	kubeletVersion := r.URL.Query().Get("kubeletVersion")
+
+	result := map[string][]resource.NodeInfo{}
+	for _, clusterStatus := range resource.GetAllCacheClusterStatus() {
+		if clusterName != "" && clusterStatus.ClusterName != clusterName {
+			continue
+		}
+		nodes := []resource.NodeInfo{}
+		for _, node := range clusterStatus.Nodes {
+			if kubeletVersion != "" && node.KubeletVersion == kubeletVersion {
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+		result[clusterStatus.ClusterName] = nodes
+	}
+	writeJSON(w, result)
+}
+
+// listControlPlane return control-plane status group by cluster name,
+// component query param only return the component, used for audit flags across clusters.
+func (s *Server) listControlPlane(w http.ResponseWriter, r *http.Request) {
+	clusterName := r.URL.Query().Get("cluster")
+	component := r.URL.Query().Get("component")
+
+	result := map[string]resource.ControlPlaneStatus{}
+	for _, clusterStatus := range resource.GetAllCacheClusterStatus() {
+		if clusterName != "" && clusterStatus.ClusterName != clusterName {
+			continue
+		}
+		cp := clusterStatus.ControlPlane
+		if component != "" {
+			components := []resource.ComponentStatus{}
+			for _, c := range cp.Components {
+				if c.Name == component {
+					components = append(components, c)
+				}
+			}
+			cp.Components = components
+		}
+		result[clusterStatus.ClusterName] = cp
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) listWorkloads(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		sru, ok := resource.GetCacheSummaryResourceWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, sru)
+		return
+	}
+	writeJSON(w, resource.GetAllCacheSummaryResource())
+}
+
+func (s *Server) listNamespaces(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		status, ok := resource.GetCacheNamespaceStatusWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, status)
+		return
+	}
+	writeJSON(w, resource.GetAllCacheNamespaceStatus())
+}
+
+func (s *Server) listAPIInventory(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		inventory, ok := resource.GetCacheAPIInventoryWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, inventory)
+		return
+	}
+	writeJSON(w, resource.GetAllCacheAPIInventory())
+}
+
+// listImages query image usages, image filter matches repository, repository:tag or repository@digest,
+// mutable=true only returns :latest and untagged images.
+func (s *Server) listImages(w http.ResponseWriter, r *http.Request) {
+	index := report.BuildImageIndex(resource.GetAllCacheImageInventory())
+	usages := index.Usages()
+	if image := r.URL.Query().Get("image"); image != "" {
+		usages = index.Find(image)
+	}
+	if r.URL.Query().Get("mutable") == "true" {
+		usages = filterImageUsages(usages, report.IsMutableImage)
+	}
+	if cluster := r.URL.Query().Get("cluster"); cluster != "" {
+		usages = filterImageUsages(usages, func(u report.ImageUsage) bool { return u.ClusterName == cluster })
+	}
+	writeJSON(w, usages)
+}
+
+func filterImageUsages(list []report.ImageUsage, match func(report.ImageUsage) bool) []report.ImageUsage {
+	result := []report.ImageUsage{}
+	for _, u := range list {
+		if match(u) {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// listVulnerabilities return findings of last advisory matching, severity filter keeps findings of the severity
+func (s *Server) listVulnerabilities(w http.ResponseWriter, r *http.Request) {
+	if advisory.AdvisoryFile == "" {
+		writeError(w, http.StatusPreconditionFailed, fmt.Errorf("advisory file not configured"))
+		return
+	}
+	vr := advisory.GetVulnerabilityReport()
+	cluster, severity := r.URL.Query().Get("cluster"), r.URL.Query().Get("severity")
+	clusters := []advisory.ClusterVulnerability{}
+	for _, cv := range vr.Clusters {
+		if cluster != "" && cv.ClusterName != cluster {
+			continue
+		}
+		if severity != "" {
+			findings := []advisory.Finding{}
+			for _, f := range cv.Findings {
+				if strings.EqualFold(f.Severity, severity) {
+					findings = append(findings, f)
+				}
+			}
+			cv.Findings = findings
+		}
+		clusters = append(clusters, cv)
+	}
+	if cluster != "" && len(clusters) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", cluster))
+		return
+	}
+	vr.Clusters = clusters
+	writeJSON(w, vr)
+}
+
+// listSecurityPosture return Pod Security Standards posture, namespace filter requires cluster
+func (s *Server) listSecurityPosture(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("cluster")
+	if name == "" {
+		writeJSON(w, resource.GetAllCacheSecurityPosture())
+		return
+	}
+	posture, ok := resource.GetCacheSecurityPostureWithClusterName(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+		return
+	}
+	if namespace := r.URL.Query().Get("namespace"); namespace != "" {
+		for _, nsp := range posture.Namespaces {
+			if nsp.Name == namespace {
+				writeJSON(w, nsp)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("namespace %s not found in cluster %s", namespace, name))
+		return
+	}
+	writeJSON(w, posture)
+}
+
+func (s *Server) listRBACExposure(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		exposure, ok := resource.GetCacheRBACExposureWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, exposure)
+		return
+	}
+	writeJSON(w, resource.GetAllCacheRBACExposure())
+}
+
+// listCertificates state filter such as Warning returns clusters having certificates in the state
+func (s *Server) listCertificates(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("cluster"); name != "" {
+		cc, ok := resource.GetCacheCertificatesWithClusterName(name)
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+			return
+		}
+		writeJSON(w, cc)
+		return
+	}
+	list := resource.GetAllCacheCertificates()
+	if state := r.URL.Query().Get("state"); state != "" {
+		result := []resource.ClusterCertificates{}
+		for _, cc := range list {
+			certs := []resource.CertificateInfo{}
+			for _, cert := range cc.Certificates {
+				if cert.State == state {
+					certs = append(certs, cert)
+				}
+			}
+			if len(certs) > 0 {
+				cc.Certificates = certs
+				result = append(result, cc)
+			}
+		}
+		list = result
+	}
+	writeJSON(w, list)
+}
+
+func (s *Server) listApps(w http.ResponseWriter, r *http.Request) {
+	apps := report.BuildAppReport(resource.GetAllCacheSummaryResource())
+	if name := r.URL.Query().Get("app"); name != "" {
+		for _, app := range apps {
+			if app.Name == name {
+				writeJSON(w, app)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Errorf("app %s not found", name))
+		return
+	}
+	writeJSON(w, apps)
+}
+
+func (s *Server) cidrReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, report.BuildCIDRReport(
+		resource.GetAllCacheClusterStatus(),
+		report.CIDRSupernet,
+		report.CIDRSuggestPrefixLen,
+		report.CIDRSuggestCount,
+	))
+}
+
+func (s *Server) capacityReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, report.BuildCapacityReport(resource.GetAllCacheClusterStatus()))
+}
+
+func (s *Server) teamReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, report.BuildTeamReport(resource.GetAllCacheNamespaceStatus()))
+}
+
+// costReport return daily cost report, format=csv return csv export
+func (s *Server) costReport(w http.ResponseWriter, r *http.Request) {
+	pricing, err := report.LoadPricing(report.PricingFile)
+	if err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+	cost := report.BuildCostReport(
+		pricing,
+		resource.GetAllCacheClusterStatus(),
+		resource.GetAllCacheNamespaceStatus(),
+		resource.GetAllCacheSummaryResource(),
+	)
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=cost-%s.csv", cost.Date))
+		if err = report.WriteCostCSV(w, cost); err != nil {
+			klog.Errorf("Write cost csv failed: %+v", err)
+		}
+		return
+	}
+	writeJSON(w, cost)
+}
+
+// upgradeReport compare clusters to target query parameter, default --target-kubernetes-version
+func (s *Server) upgradeReport(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = resource.UpgradeTargetVersion
+	}
+	if target == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("target kubernetes version not configured"))
+		return
+	}
+	upgrade, err := report.BuildUpgradeReport(target, resource.GetAllCacheClusterStatus())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, upgrade)
+}
+
+// crdReport show clusters lack CRD, filter with crd name or group
+func (s *Server) crdReport(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, report.BuildCRDReport(
+		resource.GetAllCacheAPIInventory(),
+		r.URL.Query().Get("crd"),
+		r.URL.Query().Get("group"),
+	))
+}
+
+// imageReport group images by repository and tag, image filter returns clusters running the image
+func (s *Server) imageReport(w http.ResponseWriter, r *http.Request) {
+	index := report.BuildImageIndex(resource.GetAllCacheImageInventory())
+	if image := r.URL.Query().Get("image"); image != "" {
+		writeJSON(w, map[string]interface{}{
+			"Image":    image,
+			"Clusters": index.Clusters(image),
+		})
+		return
+	}
+	writeJSON(w, index.Views())
+}
+
+// rbacReport diff risky grants of every cluster against --rbac-baseline-file
+func (s *Server) rbacReport(w http.ResponseWriter, r *http.Request) {
+	baseline, err := report.LoadRBACBaseline(report.RBACBaselineFile)
+	if err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+	writeJSON(w, report.BuildRBACDiff(baseline, resource.GetAllCacheRBACExposure()))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	http.Error(w, err.Error(), code)
+}